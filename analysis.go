@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// the scanner_output.json section with the leader analysis, next to the
+// sections of repos
+const leaderAnalysisSection = "LeaderAnalysis"
+
+// leaderAnalysisT looks at the leaders of all the scanned repos together, for
+// problems no single repo shows
+type leaderAnalysisT struct {
+	MaxChapters int
+
+	// people leading more than MaxChapters chapters
+	BusyLeaders []busyLeaderT
+
+	// leaders whose address isn't in the domain their repo kind requires, also
+	// reported as leader-email-domain findings on the repo
+	WrongDomain []leaderRefT
+
+	// names listed with more than one email address
+	NamesWithManyEmails []nameEmailsT
+
+	// chapters listing exactly the same leaders
+	SharedLeaders []sharedLeadersT
+}
+
+type busyLeaderT struct {
+	Email    string
+	Names    []string
+	Chapters []string
+}
+
+type leaderRefT struct {
+	Repo   string
+	Name   string
+	Email  string
+	Line   int
+	Domain string
+}
+
+type nameEmailsT struct {
+	Name   string
+	Emails []string
+	Repos  []string
+}
+
+type sharedLeadersT struct {
+	Emails   []string
+	Chapters []string
+}
+
+// analyzeLeaders is run once every repo has been scanned. Addresses are
+// compared ignoring case, and names ignoring case and spacing.
+func analyzeLeaders(scans []*chapterScanT, maxChapters int) leaderAnalysisT {
+	a := leaderAnalysisT{
+		MaxChapters:         maxChapters,
+		BusyLeaders:         []busyLeaderT{},
+		WrongDomain:         []leaderRefT{},
+		NamesWithManyEmails: []nameEmailsT{},
+		SharedLeaders:       []sharedLeadersT{},
+	}
+
+	sorted := append([]*chapterScanT{}, scans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	chaptersByEmail := map[string][]string{}
+	namesByEmail := map[string][]string{}
+	chaptersByLeaders := map[string][]string{}
+	emailsByName := map[string][]string{}
+	reposByName := map[string][]string{}
+	displayName := map[string]string{}
+	var emails, names, leaderLists []string
+
+	for _, c := range sorted {
+		domain := findKind(c.kind).emailDomain
+
+		var list []string
+		for _, leader := range c.status.LeaderList {
+			email := strings.ToLower(leader.Email)
+
+			if domain != "" && !inDomain(email, domain) {
+				a.WrongDomain = append(a.WrongDomain, leaderRefT{
+					Repo:   c.name,
+					Name:   leader.Name,
+					Email:  leader.Email,
+					Line:   leader.Line,
+					Domain: domain,
+				})
+			}
+
+			if name := strings.ToLower(strings.Join(strings.Fields(leader.Name), " ")); name != "" {
+				if _, ok := emailsByName[name]; !ok {
+					names = append(names, name)
+					displayName[name] = leader.Name
+				}
+				emailsByName[name] = appendOnce(emailsByName[name], email)
+				reposByName[name] = appendOnce(reposByName[name], c.name)
+			}
+
+			if c.kind != "chapter" {
+				continue
+			}
+
+			if _, ok := chaptersByEmail[email]; !ok {
+				emails = append(emails, email)
+			}
+			chaptersByEmail[email] = appendOnce(chaptersByEmail[email], c.name)
+			if leader.Name != "" {
+				namesByEmail[email] = appendOnce(namesByEmail[email], leader.Name)
+			}
+			list = appendOnce(list, email)
+		}
+
+		if len(list) > 0 {
+			sort.Strings(list)
+			key := strings.Join(list, " ")
+			if _, ok := chaptersByLeaders[key]; !ok {
+				leaderLists = append(leaderLists, key)
+			}
+			chaptersByLeaders[key] = append(chaptersByLeaders[key], c.name)
+		}
+	}
+
+	for _, email := range emails {
+		if len(chaptersByEmail[email]) > maxChapters {
+			a.BusyLeaders = append(a.BusyLeaders, busyLeaderT{Email: email, Names: append([]string{}, namesByEmail[email]...), Chapters: chaptersByEmail[email]})
+		}
+	}
+
+	for _, name := range names {
+		if len(emailsByName[name]) > 1 {
+			a.NamesWithManyEmails = append(a.NamesWithManyEmails, nameEmailsT{Name: displayName[name], Emails: emailsByName[name], Repos: reposByName[name]})
+		}
+	}
+
+	for _, key := range leaderLists {
+		if len(chaptersByLeaders[key]) > 1 {
+			a.SharedLeaders = append(a.SharedLeaders, sharedLeadersT{Emails: strings.Split(key, " "), Chapters: chaptersByLeaders[key]})
+		}
+	}
+
+	return a
+}
+
+func appendOnce(list []string, s string) []string {
+	if contains(list, s) {
+		return list
+	}
+
+	return append(list, s)
+}
+
+// writeLeaderAnalysis prints the analysis to the console. WrongDomain is left
+// out, as its leaders have already been printed as findings of their repo.
+func writeLeaderAnalysis(w io.Writer, a leaderAnalysisT) {
+	// none of the rest of the analysis is a policy violation on its own
+	if config.policy || len(a.BusyLeaders)+len(a.NamesWithManyEmails)+len(a.SharedLeaders) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Leader analysis")
+
+	for _, b := range a.BusyLeaders {
+		writeStatus(w, Medium, fmt.Sprintf("%s leads %d chapters, more than %d: %s", b.Email, len(b.Chapters), a.MaxChapters, strings.Join(b.Chapters, ", ")))
+	}
+	for _, n := range a.NamesWithManyEmails {
+		writeStatus(w, Low, fmt.Sprintf("%s is listed as %s in %s", n.Name, strings.Join(n.Emails, ", "), strings.Join(n.Repos, ", ")))
+	}
+	for _, s := range a.SharedLeaders {
+		writeStatus(w, Low, fmt.Sprintf("%s have the same leaders: %s", strings.Join(s.Chapters, ", "), strings.Join(s.Emails, ", ")))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeLeaders(t *testing.T) {
+	scan := func(name string, leaders ...leaderT) *chapterScanT {
+		c := newTestScan(name, "")
+		for i := range leaders {
+			leaders[i].Line = i + 1
+		}
+		c.status.LeaderList = leaders
+		return c
+	}
+	jane := leaderT{Name: "Jane Doe", Email: "jane@owasp.org"}
+	bob := leaderT{Name: "Bob", Email: "bob@owasp.org"}
+
+	scans := []*chapterScanT{
+		scan("www-chapter-paris", jane, leaderT{Name: "Alice", Email: "alice@example.com"}),
+		scan("www-chapter-london", jane, bob),
+		scan("www-chapter-tokyo", leaderT{Name: "jane  doe", Email: "Jane@OWASP.org"}, bob),
+		scan("www-project-zap", leaderT{Name: "Jane Doe", Email: "jane@example.com"}, leaderT{Name: "Carol", Email: "carol@example.com"}),
+	}
+
+	a := analyzeLeaders(scans, 2)
+
+	wantBusy := []busyLeaderT{{Email: "jane@owasp.org", Names: []string{"Jane Doe", "jane  doe"}, Chapters: []string{"www-chapter-london", "www-chapter-paris", "www-chapter-tokyo"}}}
+	if !reflect.DeepEqual(a.BusyLeaders, wantBusy) {
+		t.Errorf("busy leaders = %+v, want %+v", a.BusyLeaders, wantBusy)
+	}
+
+	// project leaders don't need an owasp.org address
+	wantWrongDomain := []leaderRefT{{Repo: "www-chapter-paris", Name: "Alice", Email: "alice@example.com", Line: 2, Domain: "owasp.org"}}
+	if !reflect.DeepEqual(a.WrongDomain, wantWrongDomain) {
+		t.Errorf("wrong domain = %+v, want %+v", a.WrongDomain, wantWrongDomain)
+	}
+
+	wantNames := []nameEmailsT{{Name: "Jane Doe", Emails: []string{"jane@owasp.org", "jane@example.com"}, Repos: []string{"www-chapter-london", "www-chapter-paris", "www-chapter-tokyo", "www-project-zap"}}}
+	if !reflect.DeepEqual(a.NamesWithManyEmails, wantNames) {
+		t.Errorf("names with many emails = %+v, want %+v", a.NamesWithManyEmails, wantNames)
+	}
+
+	wantShared := []sharedLeadersT{{Emails: []string{"bob@owasp.org", "jane@owasp.org"}, Chapters: []string{"www-chapter-london", "www-chapter-tokyo"}}}
+	if !reflect.DeepEqual(a.SharedLeaders, wantShared) {
+		t.Errorf("shared leaders = %+v, want %+v", a.SharedLeaders, wantShared)
+	}
+}
+
+func TestAnalyzeLeadersNone(t *testing.T) {
+	a := analyzeLeaders([]*chapterScanT{newTestScan("www-chapter-london", "")}, 2)
+
+	// empty lists, not null, in scanner_output.json
+	if a.BusyLeaders == nil || a.WrongDomain == nil || a.NamesWithManyEmails == nil || a.SharedLeaders == nil {
+		t.Errorf("analysis = %+v, want empty lists", a)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// baselineT is the file written by -write-baseline. Only the fingerprints are
+// used, the rest is there so people can see what has been suppressed.
+type baselineT struct {
+	Findings []baselineFindingT
+}
+
+type baselineFindingT struct {
+	Fingerprint string
+	RuleID      string
+	Chapter     string
+	File        string
+	Message     string
+}
+
+// fingerprints in the -baseline file, nil if there isn't one
+var baseline map[string]bool
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// fingerprint identifies a finding without using its line number, so it
+// survives unrelated edits moving the line up or down. The matched line is
+// used when there is one, otherwise the message with its numbers removed.
+// occurrence tells apart identical findings in the same file.
+func fingerprint(f Finding, occurrence int) string {
+	content := f.Snippet
+	if content == "" {
+		content = digits.ReplaceAllString(f.Message, "#")
+	}
+	content = strings.Join(strings.Fields(content), " ")
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{f.RuleID, f.Chapter, f.File, content}, "\x00")))
+	return fmt.Sprintf("%x:%d", sum[:8], occurrence)
+}
+
+// fingerprintFinding sets the fingerprint of a finding reported by the scan.
+// Paths in the message are made repo-relative first, so a baseline matches
+// scans of another clone, or of the repo with -path.
+func (c *chapterScanT) fingerprintFinding(f *Finding) {
+	if c.fingerprints == nil {
+		c.fingerprints = map[string]int{}
+	}
+
+	relative := *f
+	relative.Message = repoRelativeMessage(c.path, f.Message)
+
+	base := fingerprint(relative, 0)
+	f.Fingerprint = fingerprint(relative, c.fingerprints[base])
+	c.fingerprints[base]++
+}
+
+func loadBaseline(filename string) error {
+	if filename == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var b baselineT
+	if err := json.Unmarshal(data, &b); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	baseline = map[string]bool{}
+	for _, f := range b.Findings {
+		baseline[f.Fingerprint] = true
+	}
+
+	return nil
+}
+
+// writeBaseline saves every finding, including those already suppressed by
+// -baseline, so the new baseline covers everything known today
+func writeBaseline(scans []*chapterScanT, filename string) error {
+	b := baselineT{Findings: []baselineFindingT{}}
+	for _, c := range scans {
+		findings := append(append([]Finding{}, c.status.Findings...), c.baselined...)
+		for _, f := range findings {
+			b.Findings = append(b.Findings, baselineFindingT{
+				Fingerprint: f.Fingerprint,
+				RuleID:      f.RuleID,
+				Chapter:     f.Chapter,
+				File:        f.File,
+				Message:     f.Message,
+			})
+		}
+	}
+
+	file, err := json.MarshalIndent(b, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, file, 0644)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := Finding{RuleID: "old-wiki", Chapter: "www-chapter-london", File: "index.md", Line: 3, Message: "Old wiki link on line 3", Snippet: "See the [wiki](https://www.owasp.org/index.php/Main_Page)"}
+
+	tests := []struct {
+		name  string
+		f     func(f Finding) Finding
+		occ   int
+		equal bool
+	}{
+		{"the same finding", func(f Finding) Finding { return f }, 0, true},
+		{"moved to another line", func(f Finding) Finding { f.Line = 30; f.Message = "Old wiki link on line 30"; return f }, 0, true},
+		{"reindented", func(f Finding) Finding {
+			f.Snippet = "See the  [wiki](https://www.owasp.org/index.php/Main_Page)\t"
+			return f
+		}, 0, true},
+		{"a new severity", func(f Finding) Finding { f.Severity = High; return f }, 0, true},
+		{"another occurrence", func(f Finding) Finding { return f }, 1, false},
+		{"another line content", func(f Finding) Finding {
+			f.Snippet = "[wiki](https://www.owasp.org/index.php/Category:OWASP_Chapter)"
+			return f
+		}, 0, false},
+		{"another rule", func(f Finding) Finding { f.RuleID = "broken-link"; return f }, 0, false},
+		{"another chapter", func(f Finding) Finding { f.Chapter = "www-chapter-paris"; return f }, 0, false},
+		{"another file", func(f Finding) Finding { f.File = "info.md"; return f }, 0, false},
+	}
+
+	want := fingerprint(base, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprint(tt.f(base), tt.occ)
+			if (got == want) != tt.equal {
+				t.Errorf("fingerprint = %s, base %s, want equal %v", got, want, tt.equal)
+			}
+		})
+	}
+}
+
+func TestFingerprintWithoutSnippet(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"Low past meetings. 1 upcoming events, 2 past events", "Low past meetings. 0 upcoming events, 12 past events", true},
+		{"No leaders.md file", "No leaders.md file", true},
+		{"No leaders.md file", "No index.md file", false},
+	}
+
+	for _, tt := range tests {
+		a := fingerprint(Finding{RuleID: "r", Message: tt.a}, 0)
+		b := fingerprint(Finding{RuleID: "r", Message: tt.b}, 0)
+		if (a == b) != tt.equal {
+			t.Errorf("fingerprints of %q and %q equal %v, want %v", tt.a, tt.b, a == b, tt.equal)
+		}
+	}
+}
+
+func TestReportFindingBaseline(t *testing.T) {
+	testConfig(t)
+	saved := baseline
+	t.Cleanup(func() { baseline = saved })
+
+	dir := t.TempDir()
+	c := newTestScan("www-chapter-london", dir)
+	duplicate := Finding{RuleID: "r", File: filepath.Join(dir, "index.md"), Line: 1, Message: "same", Snippet: "same line"}
+
+	// the first of two identical findings is in the baseline, the second isn't
+	known := duplicate
+	known.Chapter = c.name
+	known.File = "index.md"
+	baseline = map[string]bool{fingerprint(known, 0): true}
+
+	c.reportFinding(duplicate)
+	duplicate.Line = 2
+	c.reportFinding(duplicate)
+
+	if len(c.baselined) != 1 || c.baselined[0].Line != 1 {
+		t.Errorf("baselined = %+v, want the finding on line 1", c.baselined)
+	}
+	if len(c.status.Findings) != 1 || c.status.Findings[0].Line != 2 {
+		t.Fatalf("findings = %+v, want the finding on line 2", c.status.Findings)
+	}
+	if got, want := c.status.Findings[0].Fingerprint, fingerprint(known, 1); got != want {
+		t.Errorf("fingerprint = %s, want %s", got, want)
+	}
+}
+
+func TestFingerprintScannedPath(t *testing.T) {
+	testConfig(t)
+	saved := baseline
+	t.Cleanup(func() { baseline = saved })
+	baseline = nil
+
+	// the same repo scanned in chapters/, cloned elsewhere for -path, and
+	// with -path from inside the checkout
+	roots := []string{
+		filepath.Join("chapters", "www-chapter-london"),
+		filepath.Join(t.TempDir(), "london"),
+		".",
+	}
+
+	var fingerprints []string
+	for _, root := range roots {
+		c := newTestScan("www-chapter-london", root)
+		site := filepath.Join(root, "_site")
+		c.reportFinding(Finding{RuleID: "site-present", File: site, Severity: Low, Message: "Site directory is present at " + site})
+
+		f := c.status.Findings[0]
+		if f.File != "_site" {
+			t.Errorf("%s: File = %q, want _site", root, f.File)
+		}
+		fingerprints = append(fingerprints, f.Fingerprint)
+	}
+
+	for i, fp := range fingerprints {
+		if fp != fingerprints[0] {
+			t.Errorf("fingerprint scanned at %s = %s, want %s as scanned at %s", roots[i], fp, fingerprints[0], roots[0])
+		}
+	}
+}
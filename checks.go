@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Check is a single policy or leading practice rule. scanChapter() offers
+// every directory entry to each enabled check for the kind of repo being
+// scanned, and runs those that match.
+type Check interface {
+	ID() string
+	Description() string
+	Severity() StatusLevelT
+	Kinds() []string // repo kinds the check applies to, empty for all
+	Matches(path string, d fs.DirEntry) bool
+	Run(c *chapterScanT, path string, d fs.DirEntry) error
+}
+
+// chapterFinisher is implemented by checks that need the results of the whole
+// chapter walk, such as the leaders found in leaders.md. Finish is called once
+// the walk is done.
+type chapterFinisher interface {
+	Finish(c *chapterScanT) error
+}
+
+type checkT struct {
+	id          string
+	description string
+	severity    StatusLevelT
+	kinds       []string
+	match       func(path string, d fs.DirEntry) bool
+	run         func(c *chapterScanT, path string, d fs.DirEntry) error
+	finish      func(c *chapterScanT) error
+	fix         func(c *chapterScanT, path string, d fs.DirEntry) error
+
+	// needs is false when the flag the check's findings need, such as
+	// -meetup, wasn't given
+	needs func() bool
+}
+
+func (c *checkT) ID() string                              { return c.id }
+func (c *checkT) Description() string                     { return c.description }
+func (c *checkT) Severity() StatusLevelT                  { return c.severity }
+func (c *checkT) Kinds() []string                         { return c.kinds }
+func (c *checkT) Matches(path string, d fs.DirEntry) bool { return c.match(path, d) }
+func (c *checkT) Run(scan *chapterScanT, path string, d fs.DirEntry) error {
+	if c.run == nil {
+		return nil
+	}
+	return c.run(scan, path, d)
+}
+
+func (c *checkT) Finish(scan *chapterScanT) error {
+	if c.finish == nil {
+		return nil
+	}
+	return c.finish(scan)
+}
+
+func (c *checkT) Fixable() bool { return c.fix != nil }
+
+func (c *checkT) Runs() bool { return c.needs == nil || c.needs() }
+
+func (c *checkT) Fix(scan *chapterScanT, path string, d fs.DirEntry) error {
+	if c.fix == nil {
+		return nil
+	}
+	return c.fix(scan, path, d)
+}
+
+var registry []Check
+
+// activeChecks is the registry filtered by -enable and -disable
+var activeChecks []Check
+
+func registerCheck(c Check) {
+	registry = append(registry, c)
+}
+
+func (sl StatusLevelT) String() string {
+	switch sl {
+	case Info:
+		return "Info"
+	case Low:
+		return "Low"
+	case Medium:
+		return "Medium"
+	case High:
+		return "High"
+	case Policy:
+		return "Policy"
+	}
+
+	return fmt.Sprintf("StatusLevelT(%d)", int(sl))
+}
+
+// Predicates used by the registered checks to select the entries they inspect
+
+func never(path string, d fs.DirEntry) bool {
+	return false
+}
+
+func isRepoDir(path string, d fs.DirEntry) bool {
+	return d.IsDir() && kindOf(d.Name()) != nil
+}
+
+func isDirNamed(name string) func(string, fs.DirEntry) bool {
+	return func(path string, d fs.DirEntry) bool {
+		return d.IsDir() && d.Name() == name
+	}
+}
+
+func isFileWithSuffix(suffix string) func(string, fs.DirEntry) bool {
+	return func(path string, d fs.DirEntry) bool {
+		return !d.IsDir() && strings.HasSuffix(path, suffix)
+	}
+}
+
+func isFileContaining(substr string) func(string, fs.DirEntry) bool {
+	return func(path string, d fs.DirEntry) bool {
+		return !d.IsDir() && strings.Contains(path, substr)
+	}
+}
+
+// isPublishedMarkdown skips migrated_content.md, as it is not shown on the site
+func isPublishedMarkdown(path string, d fs.DirEntry) bool {
+	return isFileContaining(".md")(path, d) && !strings.Contains(path, "migrated_content.md")
+}
+
+func init() {
+	// Directory checks
+	registerCheck(&checkT{
+		id:          "pages-status",
+		description: "GitHub Pages is not published for the repo",
+		severity:    Policy,
+		match:       isRepoDir,
+		needs:       func() bool { return config.pages },
+		run: func(c *chapterScanT, s string, d fs.DirEntry) error {
+			return checkPagesStatus(c, c.name)
+		},
+	})
+	registerCheck(&checkT{
+		id:          "site-present",
+		description: "_site/ is present in the repo",
+		severity:    Low,
+		match:       isDirNamed("_site"),
+		run:         checkIfSite,
+	})
+	registerCheck(&checkT{
+		id:          "jekyll-build",
+		description: "Jekyll bundle fails to build",
+		severity:    Info,
+		match:       isRepoDir,
+		needs:       func() bool { return config.build },
+		run:         checkJekyllBuilds,
+	})
+
+	// File checks
+	registerCheck(&checkT{
+		id:          "leader-count",
+		description: "Number of leaders outside the limits for the repo kind, e.g. < 2 or > 5 for chapters",
+		severity:    Policy,
+		match:       isFileWithSuffix("leaders.md"),
+		run:         checkLeaderCount,
+	})
+	registerCheck(&checkT{
+		id:          "leader-email-domain",
+		description: "Leaders in leaders.md without an email address in the domain the repo kind requires, e.g. owasp.org for chapters",
+		severity:    Policy,
+		match:       isFileWithSuffix("leaders.md"),
+		run:         checkLeaderEmailDomain,
+	})
+	registerCheck(&checkT{
+		id:          "meetup-exists",
+		description: "Meetup header present but no active Meetup for that chapter",
+		severity:    Policy,
+		kinds:       []string{"chapter"},
+		match:       isFileWithSuffix("index.md"),
+		needs:       func() bool { return config.meetup },
+		run:         checkMeetupExists,
+	})
+	registerCheck(&checkT{
+		id:          "meetup-metadata",
+		description: "Meetup header present but no metadata JavaScript for automated events",
+		severity:    Medium,
+		kinds:       []string{"chapter"},
+		match:       isFileWithSuffix(".md"),
+		run:         checkMeetupMissingMetaData,
+	})
+	registerCheck(&checkT{
+		id:          "leaders-in-copper",
+		description: "Leaders in leaders.md doesn't match Copper",
+		severity:    Medium,
+		kinds:       []string{"chapter", "project"},
+		match:       never,
+		needs:       func() bool { return config.copper },
+		finish:      checkLeadersInCopper,
+	})
+	registerCheck(&checkT{
+		id:          "migration-header",
+		description: "Automigration metadata is present and set to 1",
+		severity:    Policy,
+		match:       isFileContaining("index.md"),
+		run:         checkDefaultMigrationHeader,
+		fix:         fixDefaultMigrationHeader,
+	})
+	registerCheck(&checkT{
+		id:          "default-text",
+		description: "Default chapter template text is present",
+		severity:    Policy,
+		kinds:       []string{"chapter"},
+		match:       isFileContaining(".md"),
+		run:         checkDefaultText,
+	})
+	registerCheck(&checkT{
+		id:          "example-tab",
+		description: "Default tab tab_example.md is present",
+		severity:    Low,
+		match:       isFileContaining("tab_example.md"),
+		run:         checkDefaultExampleTab,
+		fix:         fixDefaultExampleTab,
+	})
+	registerCheck(&checkT{
+		id:          "config-yml",
+		description: "Out of date dependencies in _config.yml or Gemfile",
+		severity:    Medium,
+		match:       isConfigOrGemfile,
+		run:         checkConfigYml,
+	})
+	registerCheck(&checkT{
+		id:          "old-donate",
+		description: "Old PayPal donate mechanism is present",
+		severity:    High,
+		match:       isFileContaining(".md"),
+		run:         checkForDonate,
+	})
+	registerCheck(&checkT{
+		id:          "old-wiki",
+		description: "Old Wiki links are present",
+		severity:    Low,
+		match:       isPublishedMarkdown,
+		run:         checkForOldWiki,
+		fix:         fixOldWiki,
+	})
+	registerCheck(&checkT{
+		id:          "old-gitignore",
+		description: "Out of date .gitignore",
+		severity:    Info,
+		match:       isFileWithSuffix(".gitignore"),
+		run:         checkOldGitIgnore,
+		fix:         fixOldGitIgnore,
+	})
+	registerCheck(&checkT{
+		id:          "non-automated-platforms",
+		description: "Chapter uses an event platform other than Meetup",
+		severity:    Policy,
+		kinds:       []string{"chapter"},
+		match:       isPublishedMarkdown,
+		run:         checkNonAutomatedPlatforms,
+		finish:      checkPlatformEvents,
+	})
+	registerCheck(&checkT{
+		id:          "event-activity",
+		description: "Fewer than 3 past events, counting Meetup and, with -platforms, the other event platforms together",
+		severity:    Policy,
+		kinds:       []string{"chapter"},
+		match:       never,
+		needs:       func() bool { return config.meetup },
+		finish:      checkEventActivity,
+	})
+	registerCheck(&checkT{
+		id:          "tab-tags",
+		description: "Tab filename and title metadata is incorrect",
+		severity:    Medium,
+		match:       isRepoDir,
+		run:         checkTabTags,
+	})
+	registerCheck(&checkT{
+		id:          "project-metadata",
+		description: "Project level, type or pitch is missing from index.md",
+		severity:    Medium,
+		kinds:       []string{"project"},
+		match:       isFileWithSuffix("index.md"),
+		run:         checkProjectMetadata,
+	})
+}
+
+func splitIDs(s string) map[string]bool {
+	ids := map[string]bool{}
+	for _, id := range strings.Split(s, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids[id] = true
+		}
+	}
+
+	return ids
+}
+
+// flagGated is implemented by checks that only find anything when a flag,
+// such as -meetup, is given
+type flagGated interface {
+	Runs() bool
+}
+
+// ranRules lists the IDs of the checks that ran in full, leaving out those
+// whose flag wasn't given
+func ranRules(checks []Check) []string {
+	var ids []string
+	for _, c := range checks {
+		if gated, ok := c.(flagGated); ok && !gated.Runs() {
+			continue
+		}
+		ids = append(ids, c.ID())
+	}
+
+	return ids
+}
+
+// enabledChecks returns the registered checks selected by -enable and -disable
+func enabledChecks() ([]Check, error) {
+	known := map[string]bool{}
+	for _, c := range registry {
+		known[c.ID()] = true
+	}
+
+	enable := splitIDs(config.enable)
+	disable := splitIDs(config.disable)
+
+	for id := range enable {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown rule %q in -enable, see -list-rules", id)
+		}
+	}
+	for id := range disable {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown rule %q in -disable, see -list-rules", id)
+		}
+	}
+
+	var checks []Check
+	for _, c := range registry {
+		if len(enable) > 0 && !enable[c.ID()] {
+			continue
+		}
+		if disable[c.ID()] {
+			continue
+		}
+		checks = append(checks, c)
+	}
+
+	return checks, nil
+}
+
+func listRules() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSEVERITY\tKINDS\tDESCRIPTION")
+	for _, c := range registry {
+		kinds := strings.Join(c.Kinds(), ",")
+		if kinds == "" {
+			kinds = "all"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.ID(), c.Severity(), kinds, c.Description())
+	}
+	w.Flush()
+}
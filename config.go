@@ -2,33 +2,119 @@ package main
 
 import (
 	"flag"
+	"os"
+	"runtime"
+	"strings"
 )
 
 type configT struct {
+	baseline        string
 	build           bool
-	chapter         string
+	chapters        stringsFlag
+	connpassKey     string
+	connpassURL     string
+	copper          bool
+	copperKey       string
+	copperURL       string
+	copperUser      string
+	disable         string
+	dryRun          bool
+	enable          string
+	eventbriteToken string
+	eventbriteURL   string
+	failOn          string
+	fix             bool
+	format          string
 	gitPull         bool
+	gitRemote       string
+	githubURL       string
 	githubkey       string
+	history         string
+	html            string
+	jobs            int
+	kinds           string
+	listRules       bool
+	maxChapters     int
 	meetup          bool
-	meetup_password string
-	meetup_username string
+	meetupToken     string
+	meetupURL       string
+	org             string
+	output          string
 	pages           bool
+	path            string
+	platforms       bool
 	policy          bool
+	rules           string
+	template        string
+	writeBaseline   string
 }
 
 var config configT
 
+// stringsFlag is a flag that can be given more than once, or as a comma
+// separated list
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*s = append(*s, item)
+		}
+	}
+
+	return nil
+}
+
 func processFlags() {
+	registerFlags()
+	flag.Parse()
+}
+
+// registerFlags adds the scan flags to the command line flag set, so commands
+// that scan, such as serve, accept them too
+func registerFlags() {
+	flag.StringVar(&config.baseline, "baseline", config.baseline, "Only report findings that are not in this baseline file")
 	flag.BoolVar(&config.build, "build", config.build, "Build Jekyll site (slow, may require super user privs)")
+	flag.StringVar(&config.failOn, "fail-on", config.failOn, "Exit with status 1 if there are findings at or above this severity: info, low, medium, high or policy")
+	flag.StringVar(&config.format, "format", config.format, "Output format, json (scanner_output.json) or sarif (scanner_output.sarif)")
+	flag.BoolVar(&config.copper, "copper", config.copper, "Compare leaders.md with the leaders in Copper (slow)")
+	flag.StringVar(&config.copperKey, "copperkey", config.copperKey, "Set a Copper API key (default $COPPER_API_KEY)")
+	flag.StringVar(&config.copperURL, "copperurl", config.copperURL, "Copper API base URL")
+	flag.StringVar(&config.copperUser, "copperuser", config.copperUser, "Copper user email address the API key belongs to (default $COPPER_USER_EMAIL)")
+	flag.StringVar(&config.connpassKey, "connpasskey", config.connpassKey, "Set a Connpass API key (default $CONNPASS_API_KEY)")
+	flag.StringVar(&config.connpassURL, "connpassurl", config.connpassURL, "Connpass API base URL")
+	flag.StringVar(&config.eventbriteToken, "eventbritetoken", config.eventbriteToken, "Set an Eventbrite API token (default $EVENTBRITE_TOKEN)")
+	flag.StringVar(&config.eventbriteURL, "eventbriteurl", config.eventbriteURL, "Eventbrite API base URL")
 	flag.BoolVar(&config.gitPull, "gitpull", config.gitPull, "Update and force reset GitHub repos (slow)")
 	flag.StringVar(&config.githubkey, "githubkey", config.githubkey, "Set a GitHub API access token")
+	flag.StringVar(&config.githubURL, "githuburl", config.githubURL, "GitHub API base URL")
+	flag.StringVar(&config.history, "history", config.history, "Append a summary of each scan to this file, for scanner history, e.g. scanner_history.jsonl")
+	flag.StringVar(&config.html, "html", config.html, "Also write a self-contained HTML report to this file")
+	flag.IntVar(&config.jobs, "jobs", config.jobs, "Number of repos to scan in parallel")
+	flag.StringVar(&config.kinds, "kinds", config.kinds, "Comma separated list of repo kinds to scan: chapter, project, committee, event (default all)")
+	flag.IntVar(&config.maxChapters, "max-chapters", config.maxChapters, "Report people listed as leaders of more than this many chapters")
 	flag.BoolVar(&config.meetup, "meetup", config.meetup, "Show Meetup Group status (slow)")
+	flag.StringVar(&config.meetupToken, "meetuptoken", config.meetupToken, "Set a Meetup OAuth access token (default $MEETUP_TOKEN)")
+	flag.StringVar(&config.meetupURL, "meetupurl", config.meetupURL, "Meetup GraphQL API URL")
+	flag.StringVar(&config.org, "org", config.org, "GitHub organization the chapter repos belong to")
+	flag.StringVar(&config.output, "output", config.output, "Write the results to this file instead of scanner_output.json or scanner_output.sarif. With -path nothing is written unless it is given")
+	flag.StringVar(&config.path, "path", config.path, "Scan the repo checked out in this directory, instead of the repos in chapters/")
 	flag.BoolVar(&config.pages, "pages", config.pages, "Show chapter page status")
+	flag.BoolVar(&config.platforms, "platforms", config.platforms, "Show Eventbrite and Connpass event counts (slow)")
 	flag.BoolVar(&config.policy, "policy", config.policy, "Only show potential policy violations")
-	flag.StringVar(&config.chapter, "chapter", config.chapter, "Scan a single chapter")
-	flag.StringVar(&config.meetup_password, "password", config.meetup_password, "Meetup Password")
-	flag.StringVar(&config.meetup_username, "username", config.meetup_username, "Meetup Username")
-	flag.Parse()
+	flag.StringVar(&config.rules, "rules", config.rules, "Load link and text rules from this YAML or JSON file instead of the built in rules")
+	flag.StringVar(&config.template, "template", config.template, "Load the expected _config.yml and Gemfile settings from this YAML file instead of the built in template")
+	flag.Var(&config.chapters, "chapter", "Only scan the chapter, project, committee or event repo with this name, can be given more than once")
+	flag.StringVar(&config.disable, "disable", config.disable, "Comma separated list of rule IDs to skip")
+	flag.BoolVar(&config.fix, "fix", config.fix, "Fix what can be fixed mechanically, such as old wiki links, editing the repos in place")
+	flag.BoolVar(&config.dryRun, "dry-run", config.dryRun, "Show what -fix would change as a unified diff, without changing anything")
+	flag.StringVar(&config.enable, "enable", config.enable, "Comma separated list of rule IDs to run (default all)")
+	flag.BoolVar(&config.listRules, "list-rules", config.listRules, "List the available rules and exit")
+	flag.StringVar(&config.writeBaseline, "write-baseline", config.writeBaseline, "Write every finding to this baseline file, for use with -baseline")
 }
 
 func loadConfig() configT {
@@ -40,6 +126,21 @@ func loadConfig() configT {
 	config.meetup = false
 	config.pages = false
 	config.policy = false
+	config.jobs = runtime.NumCPU()
+	config.maxChapters = 2
+	config.format = "json"
+	config.org = "OWASP"
+	config.githubURL = "https://api.github.com"
+	config.gitRemote = "https://github.com"
+	config.copperKey = os.Getenv("COPPER_API_KEY")
+	config.copperUser = os.Getenv("COPPER_USER_EMAIL")
+	config.copperURL = "https://api.copper.com/developer_api/v1"
+	config.connpassKey = os.Getenv("CONNPASS_API_KEY")
+	config.connpassURL = "https://connpass.com/api/v2"
+	config.eventbriteToken = os.Getenv("EVENTBRITE_TOKEN")
+	config.eventbriteURL = "https://www.eventbriteapi.com/v3"
+	config.meetupToken = os.Getenv("MEETUP_TOKEN")
+	config.meetupURL = "https://api.meetup.com/gql-ext"
 
 	return config
 }
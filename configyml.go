@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The built in chapter template baseline, used unless -template is given
+//
+//go:embed template.yml
+var defaultTemplate []byte
+
+// templateBaselineT is the expected _config.yml and Gemfile for the OWASP
+// chapter template. See template.yml for a description of each field.
+type templateBaselineT struct {
+	ConfigYml struct {
+		RemoteTheme       string   `yaml:"remote_theme"`
+		RemoteThemeRef    string   `yaml:"remote_theme_ref"`
+		RequiredPlugins   []string `yaml:"required_plugins"`
+		DeprecatedPlugins []string `yaml:"deprecated_plugins"`
+		AllowedSettings   []string `yaml:"allowed_settings"`
+	} `yaml:"config_yml"`
+	Gemfile struct {
+		RequiredGems   []string `yaml:"required_gems"`
+		DeprecatedGems []string `yaml:"deprecated_gems"`
+	} `yaml:"gemfile"`
+}
+
+var chapterTemplate templateBaselineT
+
+// loadTemplate reads the chapter template baseline from filename, or the built
+// in baseline if filename is empty
+func loadTemplate(filename string) error {
+	data := defaultTemplate
+	if filename != "" {
+		var err error
+		data, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := yaml.Unmarshal(data, &chapterTemplate); err != nil {
+		if filename == "" {
+			filename = "built in template"
+		}
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return nil
+}
+
+func isConfigOrGemfile(path string, d fs.DirEntry) bool {
+	return !d.IsDir() && (d.Name() == "_config.yml" || d.Name() == "Gemfile")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Out of date dependencies in _config.yml
+func checkConfigYml(c *chapterScanT, filename string, d fs.DirEntry) error {
+	// Only the files at the top of the repo are used by Jekyll
+	if filepath.Dir(filename) != filepath.Clean(c.path) {
+		return nil
+	}
+
+	if d.Name() == "Gemfile" {
+		return checkGemfile(c, filename)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	found := func(sl StatusLevelT, line int, msg string) {
+		snippet := ""
+		if line > 0 && line <= len(lines) {
+			snippet = lines[line-1]
+		}
+
+		c.reportFinding(Finding{
+			RuleID:   "config-yml",
+			Severity: sl,
+			File:     filename,
+			Line:     line,
+			Message:  msg,
+			Snippet:  snippet,
+		})
+		c.status.ConfigYml = true
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		found(Medium, 0, fmt.Sprintf("Unable to parse %s: %v", filename, err))
+		return nil
+	}
+
+	// An empty file has no document node
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		found(Medium, 0, "No remote_theme in "+filename)
+		return nil
+	}
+
+	expected := chapterTemplate.ConfigYml
+	settings := doc.Content[0].Content
+	hasRemoteTheme := false
+
+	for i := 0; i+1 < len(settings); i += 2 {
+		key, value := settings[i], settings[i+1]
+
+		switch key.Value {
+		case "remote_theme":
+			hasRemoteTheme = true
+
+			repo, ref := value.Value, ""
+			if at := strings.Index(repo, "@"); at >= 0 {
+				repo, ref = repo[:at], repo[at+1:]
+			}
+
+			if !strings.EqualFold(repo, expected.RemoteTheme) {
+				found(High, key.Line, fmt.Sprintf("remote_theme %s is not %s in %s on line %d", value.Value, expected.RemoteTheme, filename, key.Line))
+			} else if expected.RemoteThemeRef != "" && ref != expected.RemoteThemeRef {
+				found(Low, key.Line, fmt.Sprintf("remote_theme %s is not pinned to %s in %s on line %d", value.Value, expected.RemoteThemeRef, filename, key.Line))
+			}
+
+		case "theme":
+			found(Medium, key.Line, fmt.Sprintf("theme %s is set in %s on line %d, the chapter template uses remote_theme", value.Value, filename, key.Line))
+
+		case "plugins":
+			var plugins []string
+			for _, plugin := range value.Content {
+				plugins = append(plugins, plugin.Value)
+				if contains(expected.DeprecatedPlugins, plugin.Value) {
+					found(Low, plugin.Line, fmt.Sprintf("Deprecated plugin %s in %s on line %d", plugin.Value, filename, plugin.Line))
+				}
+			}
+
+			for _, required := range expected.RequiredPlugins {
+				if !contains(plugins, required) {
+					found(Medium, key.Line, fmt.Sprintf("Plugin %s is missing from %s", required, filename))
+				}
+			}
+
+		default:
+			if !contains(expected.AllowedSettings, key.Value) {
+				found(Low, key.Line, fmt.Sprintf("Unexpected setting %s in %s on line %d", key.Value, filename, key.Line))
+			}
+		}
+	}
+
+	if !hasRemoteTheme {
+		found(Medium, 0, "No remote_theme in "+filename)
+	}
+
+	return nil
+}
+
+var gemRegexp = regexp.MustCompile(`^\s*gem\s+["']([^"']+)["']`)
+
+func checkGemfile(c *chapterScanT, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	expected := chapterTemplate.Gemfile
+	var gems []string
+
+	// Splits on newlines by default.
+	scanner := bufio.NewScanner(f)
+
+	line := 1
+	// https://golang.org/pkg/bufio/#Scanner.Scan
+	for scanner.Scan() {
+		if m := gemRegexp.FindStringSubmatch(scanner.Text()); m != nil {
+			gems = append(gems, m[1])
+
+			if contains(expected.DeprecatedGems, m[1]) {
+				c.reportFinding(Finding{
+					RuleID:   "config-yml",
+					Severity: Low,
+					File:     filename,
+					Line:     line,
+					Column:   column(scanner.Text(), m[1]),
+					Message:  fmt.Sprintf("Deprecated gem %s in %s on line %d", m[1], filename, line),
+					Snippet:  scanner.Text(),
+				})
+				c.status.ConfigYml = true
+			}
+		}
+
+		line++
+	}
+
+	for _, required := range expected.RequiredGems {
+		if !contains(gems, required) {
+			c.reportFinding(Finding{
+				RuleID:   "config-yml",
+				Severity: Medium,
+				File:     filename,
+				Message:  fmt.Sprintf("Gem %s is missing from %s", required, filename),
+			})
+			c.status.ConfigYml = true
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// copperClientT fetches chapter leader rosters from the Copper CRM developer
+// API. Chapters are Copper projects named after the chapter repo, with the
+// leaders as related people.
+// https://developer.copper.com/
+type copperClientT struct {
+	baseURL string
+	apiKey  string
+	user    string
+	client  *http.Client
+}
+
+type copperProjectT struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type copperRelatedT struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type copperPersonT struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Emails []struct {
+		Email    string `json:"email"`
+		Category string `json:"category"`
+	} `json:"emails"`
+}
+
+func newCopperClient() *copperClientT {
+	return &copperClientT{
+		baseURL: strings.TrimSuffix(config.copperURL, "/"),
+		apiKey:  config.copperKey,
+		user:    config.copperUser,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (cc *copperClientT) do(method string, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Copper allows a few requests a second, so back off once if we are told to
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, cc.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-PW-AccessToken", cc.apiKey)
+		req.Header.Set("X-PW-Application", "developer_api")
+		req.Header.Set("X-PW-UserEmail", cc.user)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := cc.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if wait < 1 {
+				wait = 1
+			}
+			time.Sleep(time.Duration(wait) * time.Second)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("copper %s %s: %s", method, path, resp.Status)
+		}
+
+		return json.Unmarshal(data, out)
+	}
+}
+
+// copperLeaderT is a leader Copper has for a chapter, with all their
+// addresses
+type copperLeaderT struct {
+	Name   string
+	Emails []string
+}
+
+// leaders returns the leaders Copper has for the chapter. found is false if
+// Copper has no project for the chapter.
+func (cc *copperClientT) leaders(chapter string) (leaders []copperLeaderT, found bool, err error) {
+	var projects []copperProjectT
+	err = cc.do("POST", "/projects/search", map[string]interface{}{"name": chapter}, &projects)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// search is a partial match, so www-chapter-london also finds www-chapter-london-ontario
+	var project *copperProjectT
+	for i := range projects {
+		if strings.EqualFold(projects[i].Name, chapter) {
+			project = &projects[i]
+			break
+		}
+	}
+	if project == nil {
+		return nil, false, nil
+	}
+
+	var related []copperRelatedT
+	err = cc.do("GET", fmt.Sprintf("/projects/%d/related/people", project.ID), nil, &related)
+	if err != nil {
+		return nil, true, err
+	}
+
+	for _, r := range related {
+		var person copperPersonT
+		err = cc.do("GET", fmt.Sprintf("/people/%d", r.ID), nil, &person)
+		if err != nil {
+			return nil, true, err
+		}
+
+		leader := copperLeaderT{Name: person.Name}
+		for _, e := range person.Emails {
+			leader.Emails = append(leader.Emails, e.Email)
+		}
+		leaders = append(leaders, leader)
+	}
+
+	return leaders, true, nil
+}
+
+// compareLeaders returns the leaders.md addresses that aren't any Copper
+// leader's, and the Copper leaders with none of their addresses in
+// leaders.md. Leaders often have several addresses in Copper, so listing any
+// one of them is enough. Addresses are compared ignoring case.
+func compareLeaders(listed []string, copper []copperLeaderT) (notInCopper []string, notListed []copperLeaderT) {
+	inListed := map[string]bool{}
+	for _, email := range listed {
+		inListed[strings.ToLower(email)] = true
+	}
+
+	inCopper := map[string]bool{}
+	for _, leader := range copper {
+		found := false
+		for _, email := range leader.Emails {
+			inCopper[strings.ToLower(email)] = true
+			if inListed[strings.ToLower(email)] {
+				found = true
+			}
+		}
+		if !found {
+			notListed = append(notListed, leader)
+		}
+	}
+
+	for _, email := range listed {
+		if !inCopper[strings.ToLower(email)] {
+			notInCopper = append(notInCopper, email)
+		}
+	}
+	sort.Strings(notInCopper)
+	sort.Slice(notListed, func(i, j int) bool { return notListed[i].String() < notListed[j].String() })
+
+	return notInCopper, notListed
+}
+
+// String is the leader's name and first address, e.g. for
+// CopperLeadersNotListed
+func (l copperLeaderT) String() string {
+	switch {
+	case len(l.Emails) == 0:
+		return l.Name
+	case l.Name == "":
+		return l.Emails[0]
+	}
+
+	return fmt.Sprintf("%s <%s>", l.Name, l.Emails[0])
+}
+
+// Leaders in leaders.md doesn’t match Copper
+func checkLeadersInCopper(c *chapterScanT) error {
+	if !config.copper {
+		return nil
+	}
+
+	if config.copperKey == "" || config.copperUser == "" {
+		return fmt.Errorf("-copper needs a Copper API key and user email, see -copperkey and -copperuser")
+	}
+
+	copperLeaders, found, err := newCopperClient().leaders(c.name)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		c.reportFinding(Finding{
+			RuleID:   "leaders-in-copper",
+			Severity: Medium,
+			Message:  "No Copper project for " + c.name,
+		})
+		return nil
+	}
+
+	notInCopper, notListed := compareLeaders(c.status.LeaderEmails, copperLeaders)
+	c.status.LeadersNotInCopper = notInCopper
+	c.status.CopperLeadersNotListed = nil
+
+	for _, email := range c.status.LeadersNotInCopper {
+		c.reportFinding(Finding{
+			RuleID:   "leaders-in-copper",
+			Severity: Medium,
+			File:     filepath.Join(c.path, "leaders.md"),
+			Message:  fmt.Sprintf("%s is listed in leaders.md but is not a leader of %s in Copper", email, c.name),
+			Snippet:  email,
+		})
+	}
+
+	for _, leader := range notListed {
+		c.status.CopperLeadersNotListed = append(c.status.CopperLeadersNotListed, leader.String())
+		c.reportFinding(Finding{
+			RuleID:   "leaders-in-copper",
+			Severity: Medium,
+			File:     filepath.Join(c.path, "leaders.md"),
+			Message:  fmt.Sprintf("%s is a leader of %s in Copper but is not listed in leaders.md", leader, c.name),
+			Snippet:  strings.Join(leader.Emails, ", "),
+		})
+	}
+
+	return nil
+}
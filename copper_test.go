@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareLeaders(t *testing.T) {
+	jane := copperLeaderT{Name: "Jane Doe", Emails: []string{"jane@example.com", "jane.doe@owasp.org"}}
+	bob := copperLeaderT{Name: "Bob", Emails: []string{"bob@owasp.org"}}
+	nameless := copperLeaderT{Emails: []string{"x@owasp.org"}}
+
+	tests := []struct {
+		name            string
+		listed          []string
+		copper          []copperLeaderT
+		wantNotInCopper []string
+		wantNotListed   []string
+	}{
+		{
+			name:   "every leader listed",
+			listed: []string{"jane.doe@owasp.org", "bob@owasp.org"},
+			copper: []copperLeaderT{jane, bob},
+		},
+		{
+			name:   "one of several addresses is enough",
+			listed: []string{"JANE@example.com", "bob@owasp.org"},
+			copper: []copperLeaderT{jane, bob},
+		},
+		{
+			name:          "a person missing from leaders.md is reported once",
+			listed:        []string{"bob@owasp.org"},
+			copper:        []copperLeaderT{jane, bob},
+			wantNotListed: []string{"Jane Doe <jane@example.com>"},
+		},
+		{
+			name:            "a leader missing from Copper",
+			listed:          []string{"bob@owasp.org", "new@owasp.org", "jane.doe@owasp.org"},
+			copper:          []copperLeaderT{jane, bob},
+			wantNotInCopper: []string{"new@owasp.org"},
+		},
+		{
+			name:            "no Copper leaders",
+			listed:          []string{"bob@owasp.org"},
+			wantNotInCopper: []string{"bob@owasp.org"},
+		},
+		{
+			name:          "a person without a name is reported by address",
+			copper:        []copperLeaderT{nameless},
+			wantNotListed: []string{"x@owasp.org"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notInCopper, notListed := compareLeaders(tt.listed, tt.copper)
+
+			var names []string
+			for _, leader := range notListed {
+				names = append(names, leader.String())
+			}
+			assertStrings(t, "notInCopper", notInCopper, tt.wantNotInCopper)
+			assertStrings(t, "notListed", names, tt.wantNotListed)
+		})
+	}
+}
+
+// fakeCopper is a Copper API with one project, www-chapter-london, whose
+// leaders are Jane, with two addresses, and Bob
+func fakeCopper(t *testing.T) *httptest.Server {
+	people := map[string]copperPersonT{}
+	addPerson := func(id string, name string, emails ...string) {
+		p := copperPersonT{Name: name}
+		for _, email := range emails {
+			p.Emails = append(p.Emails, struct {
+				Email    string `json:"email"`
+				Category string `json:"category"`
+			}{Email: email, Category: "work"})
+		}
+		people[id] = p
+	}
+	addPerson("1", "Jane Doe", "jane@example.com", "jane.doe@owasp.org")
+	addPerson("2", "Bob", "bob@owasp.org")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-PW-AccessToken") != "key" || r.Header.Get("X-PW-UserEmail") != "me@owasp.org" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/projects/search":
+			var search struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&search)
+
+			// a partial match, like the real search
+			var projects []copperProjectT
+			for id, name := range map[int64]string{7: "www-chapter-london", 8: "www-chapter-london-ontario"} {
+				if strings.Contains(name, search.Name) {
+					projects = append(projects, copperProjectT{ID: id, Name: name})
+				}
+			}
+			json.NewEncoder(w).Encode(projects)
+
+		case r.Method == "GET" && r.URL.Path == "/projects/7/related/people":
+			json.NewEncoder(w).Encode([]copperRelatedT{{ID: 1, Type: "person"}, {ID: 2, Type: "person"}})
+
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/people/"):
+			p, ok := people[strings.TrimPrefix(r.URL.Path, "/people/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(p)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestCheckLeadersInCopper(t *testing.T) {
+	testConfig(t)
+	server := fakeCopper(t)
+	config.copper = true
+	config.copperURL = server.URL
+	config.copperKey = "key"
+	config.copperUser = "me@owasp.org"
+
+	c := newTestScan("www-chapter-london", t.TempDir())
+	c.status.LeaderEmails = []string{"jane.doe@owasp.org", "alice@owasp.org"}
+
+	if err := checkLeadersInCopper(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assertStrings(t, "LeadersNotInCopper", c.status.LeadersNotInCopper, []string{"alice@owasp.org"})
+	assertStrings(t, "CopperLeadersNotListed", c.status.CopperLeadersNotListed, []string{"Bob <bob@owasp.org>"})
+	assertStrings(t, "findings", findingMessages(c.status.Findings), []string{
+		"alice@owasp.org is listed in leaders.md but is not a leader of www-chapter-london in Copper",
+		"Bob <bob@owasp.org> is a leader of www-chapter-london in Copper but is not listed in leaders.md",
+	})
+}
+
+func TestCheckLeadersInCopperNoProject(t *testing.T) {
+	testConfig(t)
+	server := fakeCopper(t)
+	config.copper = true
+	config.copperURL = server.URL
+	config.copperKey = "key"
+	config.copperUser = "me@owasp.org"
+
+	c := newTestScan("www-chapter-lon", t.TempDir())
+	if err := checkLeadersInCopper(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assertStrings(t, "findings", findingMessages(c.status.Findings), []string{"No Copper project for www-chapter-lon"})
+}
+
+func TestCheckLeadersInCopperBadKey(t *testing.T) {
+	testConfig(t)
+	server := fakeCopper(t)
+	config.copper = true
+	config.copperURL = server.URL
+	config.copperKey = "wrong"
+	config.copperUser = "me@owasp.org"
+
+	c := newTestScan("www-chapter-london", t.TempDir())
+	if err := checkLeadersInCopper(c); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want a 401 error", err)
+	}
+}
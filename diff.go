@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// outputDiffT is what changed between two runs of the scanner
+type outputDiffT struct {
+	Added   []repoRefT
+	Removed []repoRefT
+	Changed []repoDiffT
+}
+
+type repoRefT struct {
+	Section string
+	Repo    string
+}
+
+type repoDiffT struct {
+	Section    string
+	Repo       string
+	Changes    []fieldChangeT
+	Introduced []Finding
+	Resolved   []Finding
+}
+
+type fieldChangeT struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// runDiff compares two scanner_output.json files
+func runDiff(args []string) error {
+	format := "text"
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&format, "format", format, "Output format, text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scanner diff [-format text|json] old.json new.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("diff needs two scanner_output.json files")
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown -format %q, expected text or json", format)
+	}
+
+	before, beforeInfo, err := readOutput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, afterInfo, err := readOutput(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if beforeInfo.baseline() != afterInfo.baseline() {
+		// the findings in only one baseline would look introduced or resolved
+		return fmt.Errorf("%s was scanned with %s and %s with %s, scan both with the same -baseline before comparing",
+			fs.Arg(0), describeBaseline(beforeInfo.baseline()), fs.Arg(1), describeBaseline(afterInfo.baseline()))
+	}
+
+	d := diffOutputs(before, after, func(id string) bool { return beforeInfo.ran(id) && afterInfo.ran(id) })
+
+	if format == "json" {
+		file, err := json.MarshalIndent(d, "", " ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(file))
+		return err
+	}
+
+	writeDiff(os.Stdout, d)
+	return nil
+}
+
+func describeBaseline(baseline string) string {
+	if baseline == "" {
+		return "no -baseline"
+	}
+	return "-baseline " + baseline
+}
+
+// readOutput reads the repo sections of a scanner_output.json, and how the
+// scan was run, nil if it wasn't recorded. Files written before repos were
+// split into sections only have chapters, keyed by name.
+func readOutput(filename string) (scannerOutputT, *scanInfoT, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	var info *scanInfoT
+	if raw, ok := sections[scanInfoSection]; ok {
+		info = &scanInfoT{}
+		if err := json.Unmarshal(raw, info); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	output := scannerOutputT{}
+	for section, raw := range sections {
+		if section == leaderAnalysisSection || section == scanInfoSection {
+			continue
+		}
+
+		var repos map[string]*chapterStatusT
+		if err := json.Unmarshal(raw, &repos); err != nil {
+			output = nil
+			break
+		}
+		output[section] = repos
+	}
+	if output != nil {
+		return output, info, nil
+	}
+
+	var chapters map[string]*chapterStatusT
+	if err := json.Unmarshal(data, &chapters); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return scannerOutputT{"Chapters": chapters}, nil, nil
+}
+
+// diffOutputs compares two outputs. Only the rules both runs ran are
+// compared, so a run without -meetup doesn't look like every Meetup group has
+// gone.
+func diffOutputs(before scannerOutputT, after scannerOutputT, compared func(id string) bool) outputDiffT {
+	d := outputDiffT{Added: []repoRefT{}, Removed: []repoRefT{}, Changed: []repoDiffT{}}
+
+	var sections []string
+	for section := range before {
+		sections = append(sections, section)
+	}
+	for section := range after {
+		if before[section] == nil {
+			sections = append(sections, section)
+		}
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		for _, repo := range repoNames(before[section], after[section]) {
+			was, now := before[section][repo], after[section][repo]
+
+			switch {
+			case was == nil:
+				d.Added = append(d.Added, repoRefT{Section: section, Repo: repo})
+			case now == nil:
+				d.Removed = append(d.Removed, repoRefT{Section: section, Repo: repo})
+			default:
+				rd := diffRepo(was, now, compared)
+				if len(rd.Changes) > 0 || len(rd.Introduced) > 0 || len(rd.Resolved) > 0 {
+					rd.Section, rd.Repo = section, repo
+					d.Changed = append(d.Changed, rd)
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+func diffRepo(before *chapterStatusT, after *chapterStatusT, compared func(id string) bool) repoDiffT {
+	rd := repoDiffT{Changes: []fieldChangeT{}, Introduced: []Finding{}, Resolved: []Finding{}}
+
+	change := func(field string, before string, after string) {
+		if before != after {
+			rd.Changes = append(rd.Changes, fieldChangeT{Field: field, Old: before, New: after})
+		}
+	}
+	change("Leaders", strconv.Itoa(before.Leaders), strconv.Itoa(after.Leaders))
+	if compared("meetup-exists") {
+		change("Meetup", before.Meetup.String(), after.Meetup.String())
+	}
+	if compared("pages-status") {
+		change("GitHub", before.GitHub.String(), after.GitHub.String())
+	}
+
+	oldFindings := findingsByFingerprint(before.Findings)
+	newFindings := findingsByFingerprint(after.Findings)
+	for _, f := range after.Findings {
+		if _, ok := oldFindings[f.Fingerprint]; !ok && compared(f.RuleID) {
+			rd.Introduced = append(rd.Introduced, f)
+		}
+	}
+	for _, f := range before.Findings {
+		if _, ok := newFindings[f.Fingerprint]; !ok && compared(f.RuleID) {
+			rd.Resolved = append(rd.Resolved, f)
+		}
+	}
+
+	return rd
+}
+
+// findingsByFingerprint fills in the fingerprints of findings from outputs
+// written before findings had them
+func findingsByFingerprint(findings []Finding) map[string]Finding {
+	seen := map[string]int{}
+	byFingerprint := map[string]Finding{}
+	for i := range findings {
+		f := &findings[i]
+		if f.Fingerprint == "" {
+			base := fingerprint(*f, 0)
+			f.Fingerprint = fingerprint(*f, seen[base])
+			seen[base]++
+		}
+		byFingerprint[f.Fingerprint] = *f
+	}
+
+	return byFingerprint
+}
+
+// repoNames returns the repos in either section, sorted by name
+func repoNames(before map[string]*chapterStatusT, after map[string]*chapterStatusT) []string {
+	var names []string
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func writeDiff(w io.Writer, d outputDiffT) {
+	introduced, resolved := 0, 0
+	for _, rd := range d.Changed {
+		introduced += len(rd.Introduced)
+		resolved += len(rd.Resolved)
+	}
+
+	for _, r := range d.Added {
+		fmt.Fprintf(w, "+ %s added\n", r.Repo)
+	}
+	for _, r := range d.Removed {
+		fmt.Fprintf(w, "- %s removed\n", r.Repo)
+	}
+
+	for _, rd := range d.Changed {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, rd.Repo)
+		for _, c := range rd.Changes {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", c.Field, c.Old, c.New)
+		}
+		for _, f := range rd.Introduced {
+			fmt.Fprintf(w, "  + %s: %s\n", f.Severity, f.Message)
+		}
+		for _, f := range rd.Resolved {
+			fmt.Fprintf(w, "  - %s: %s\n", f.Severity, f.Message)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d repos added, %d removed, %d changed, %d findings introduced, %d resolved\n",
+		len(d.Added), len(d.Removed), len(d.Changed), introduced, resolved)
+}
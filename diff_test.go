@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRepo(t *testing.T) {
+	fingerprinted := func(f Finding) Finding {
+		f.Fingerprint = fingerprint(f, 0)
+		return f
+	}
+	// written before findings had fingerprints
+	legacy := Finding{RuleID: "old-wiki", File: "index.md", Message: "Old wiki link", Snippet: "https://www.owasp.org/index.php/Main_Page"}
+	oldWiki := fingerprinted(legacy)
+	noMeetup := fingerprinted(Finding{RuleID: "meetup-exists", Message: "Meetup group london does not exist"})
+	noLeaders := fingerprinted(Finding{RuleID: "leaders-file", Message: "No leaders.md file"})
+	all := func(id string) bool { return true }
+	withoutMeetup := func(id string) bool { return id != "meetup-exists" }
+
+	tests := []struct {
+		name           string
+		before, after  chapterStatusT
+		compared       func(id string) bool
+		wantChanges    []fieldChangeT
+		wantIntroduced []string
+		wantResolved   []string
+	}{
+		{
+			name:     "nothing changed",
+			before:   chapterStatusT{Leaders: 2, Meetup: active, Findings: []Finding{oldWiki}},
+			after:    chapterStatusT{Leaders: 2, Meetup: active, Findings: []Finding{oldWiki}},
+			compared: all,
+		},
+		{
+			name:     "status fields",
+			before:   chapterStatusT{Leaders: 2, Meetup: active, GitHub: active},
+			after:    chapterStatusT{Leaders: 1, Meetup: inactive, GitHub: nonexistant},
+			compared: all,
+			wantChanges: []fieldChangeT{
+				{"Leaders", "2", "1"},
+				{"Meetup", "active", "inactive"},
+				{"GitHub", "active", "nonexistent"},
+			},
+		},
+		{
+			name:           "findings introduced and resolved",
+			before:         chapterStatusT{Findings: []Finding{oldWiki}},
+			after:          chapterStatusT{Findings: []Finding{noLeaders}},
+			compared:       all,
+			wantIntroduced: []string{"No leaders.md file"},
+			wantResolved:   []string{"Old wiki link"},
+		},
+		{
+			name:     "a rule that didn't run in both",
+			before:   chapterStatusT{Meetup: active, Findings: []Finding{oldWiki}},
+			after:    chapterStatusT{Meetup: nonexistant, Findings: []Finding{oldWiki, noMeetup}},
+			compared: withoutMeetup,
+		},
+		{
+			name:         "a rule that didn't run in both doesn't hide the others",
+			before:       chapterStatusT{Findings: []Finding{noMeetup, oldWiki}},
+			after:        chapterStatusT{},
+			compared:     withoutMeetup,
+			wantResolved: []string{"Old wiki link"},
+		},
+		{
+			name:     "findings without fingerprints",
+			before:   chapterStatusT{Findings: []Finding{legacy}},
+			after:    chapterStatusT{Findings: []Finding{oldWiki}},
+			compared: all,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rd := diffRepo(&tt.before, &tt.after, tt.compared)
+
+			if len(rd.Changes) > 0 || len(tt.wantChanges) > 0 {
+				if !reflect.DeepEqual(rd.Changes, tt.wantChanges) {
+					t.Errorf("changes = %v, want %v", rd.Changes, tt.wantChanges)
+				}
+			}
+			assertStrings(t, "introduced", findingMessages(rd.Introduced), tt.wantIntroduced)
+			assertStrings(t, "resolved", findingMessages(rd.Resolved), tt.wantResolved)
+		})
+	}
+}
+
+func TestDiffOutputs(t *testing.T) {
+	before := scannerOutputT{
+		"Chapters": {
+			"www-chapter-london": {Leaders: 2},
+			"www-chapter-paris":  {Leaders: 1},
+		},
+	}
+	after := scannerOutputT{
+		"Chapters": {
+			"www-chapter-london": {Leaders: 3},
+			"www-chapter-tokyo":  {Leaders: 1},
+		},
+		"Projects": {
+			"www-project-zap": {Leaders: 4},
+		},
+	}
+
+	d := diffOutputs(before, after, func(id string) bool { return true })
+
+	wantAdded := []repoRefT{{"Chapters", "www-chapter-tokyo"}, {"Projects", "www-project-zap"}}
+	if !reflect.DeepEqual(d.Added, wantAdded) {
+		t.Errorf("added = %v, want %v", d.Added, wantAdded)
+	}
+	wantRemoved := []repoRefT{{"Chapters", "www-chapter-paris"}}
+	if !reflect.DeepEqual(d.Removed, wantRemoved) {
+		t.Errorf("removed = %v, want %v", d.Removed, wantRemoved)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Repo != "www-chapter-london" || d.Changed[0].Section != "Chapters" {
+		t.Errorf("changed = %+v, want www-chapter-london", d.Changed)
+	}
+}
+
+func TestScanInfoRan(t *testing.T) {
+	info := &scanInfoT{Rules: []string{"old-wiki", "meetup-exists"}}
+
+	tests := []struct {
+		info *scanInfoT
+		id   string
+		want bool
+	}{
+		{info, "old-wiki", true},
+		{info, "meetup-exists", true},
+		{info, "pages-status", false},
+		{&scanInfoT{}, "old-wiki", false},
+		// output written before the scan was recorded
+		{nil, "old-wiki", true},
+		{nil, "meetup-exists", false},
+		{nil, "leaders-in-copper", false},
+		{nil, "no-such-rule", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.info.ran(tt.id); got != tt.want {
+			t.Errorf("%+v.ran(%q) = %v, want %v", tt.info, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestRunDiffRefusesBaselines(t *testing.T) {
+	testConfig(t)
+	chapters := map[string]*chapterStatusT{"www-chapter-london": {}}
+	rules := []string{"old-wiki"}
+
+	none := writeTestOutput(t, chapters, scanInfoT{Rules: rules})
+	old := writeTestOutput(t, chapters, scanInfoT{Rules: rules, Baseline: "old.json"})
+	other := writeTestOutput(t, chapters, scanInfoT{Rules: rules, Baseline: "new.json"})
+	legacy := filepath.Join(t.TempDir(), "legacy.json")
+	data, err := json.Marshal(chapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(legacy, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{"no baselines", none, none, ""},
+		{"the same baseline", old, old, ""},
+		{"no record of the scan", legacy, none, ""},
+		{"a baseline only after", none, old, "scanned with no -baseline and " + old + " with -baseline old.json"},
+		{"a baseline only before", old, legacy, "scanned with -baseline old.json and " + legacy + " with no -baseline"},
+		{"different baselines", old, other, "scanned with -baseline old.json and " + other + " with -baseline new.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDiff([]string{"-format", "json", tt.before, tt.after})
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("err = %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
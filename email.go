@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The built in email template, used unless -template is given. The template
+// writes the headers, at least a Subject, then a blank line and the body.
+//
+//go:embed email.tmpl
+var defaultEmailTemplate string
+
+// digestT is what the email template is given for each repo
+type digestT struct {
+	Repo           string
+	Kind           string
+	RepoURL        string
+	Findings       []digestFindingT
+	PolicyFindings int
+}
+
+type digestFindingT struct {
+	Severity StatusLevelT
+	Message  string
+	Link     string // empty if the finding isn't in a file
+}
+
+// runNotifyEmail emails the leaders of each repo in the -input file a digest
+// of its findings, using the addresses in leaders.md
+func runNotifyEmail(args []string) error {
+	input := "scanner_output.json"
+	templateFile := ""
+	severity := "low"
+	optOutFile := ""
+	dryRun := false
+	dir := "emails"
+	server := "localhost:25"
+	from := ""
+	user := ""
+	password := os.Getenv("SMTP_PASSWORD")
+
+	fs := flag.NewFlagSet("notify-email", flag.ExitOnError)
+	fs.StringVar(&input, "input", input, "scanner_output.json with the findings to send")
+	fs.StringVar(&templateFile, "template", templateFile, "Render the emails with this Go template instead of the built in one")
+	fs.StringVar(&severity, "severity", severity, "Only send findings at or above this severity: info, low, medium, high or policy")
+	fs.StringVar(&optOutFile, "opt-out", optOutFile, "File of email addresses and repo names that don't want the digest, one per line")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "Write each email to an .eml file in -dir instead of sending it")
+	fs.StringVar(&dir, "dir", dir, "Directory for the -dry-run .eml files")
+	fs.StringVar(&server, "smtp", server, "SMTP server host:port")
+	fs.StringVar(&user, "smtp-user", user, "SMTP user name, if the server needs one")
+	fs.StringVar(&password, "smtp-password", password, "SMTP password (default $SMTP_PASSWORD)")
+	fs.StringVar(&from, "from", from, "Address the emails are sent from, e.g. \"OWASP Policy Scanner <scanner@example.org>\"")
+	fs.StringVar(&config.org, "org", config.org, "GitHub organization the repos belong to, for the links")
+	fs.Var(&config.chapters, "chapter", "Only email the leaders of the repo with this name, can be given more than once")
+	fs.StringVar(&config.kinds, "kinds", config.kinds, "Comma separated list of repo kinds to email: chapter, project, committee, event (default all)")
+	fs.Parse(args)
+
+	if from == "" {
+		return fmt.Errorf("notify-email needs a -from address")
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("-from: %v", err)
+	}
+
+	minSeverity, err := parseStatusLevel(severity)
+	if err != nil {
+		return err
+	}
+
+	kinds, err := selectedKinds()
+	if err != nil {
+		return err
+	}
+
+	output, _, err := readOutput(input)
+	if err != nil {
+		return err
+	}
+
+	text := defaultEmailTemplate
+	if templateFile != "" {
+		data, err := ioutil.ReadFile(templateFile)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("email").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	optOut, err := readOptOut(optOutFile)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if user != "" {
+		host, _, err := net.SplitHostPort(server)
+		if err != nil {
+			return fmt.Errorf("-smtp: %v", err)
+		}
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+
+	if dryRun {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	sent, optedOut, noLeaders, failed := 0, 0, 0, 0
+	for _, kind := range kinds {
+		repos := output[kind.section]
+
+		var names []string
+		for name := range repos {
+			if selectedRepo(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			status := repos[name]
+
+			digest := newDigest(name, kind.name, status, minSeverity)
+			if len(digest.Findings) == 0 {
+				continue
+			}
+
+			if optOut[strings.ToLower(name)] {
+				fmt.Printf("%s: opted out\n", name)
+				optedOut++
+				continue
+			}
+
+			to := recipients(status.LeaderEmails, optOut)
+			if len(to) == 0 {
+				fmt.Printf("%s: no leader email addresses in leaders.md to send to\n", name)
+				noLeaders++
+				continue
+			}
+
+			msg, err := renderEmail(tmpl, digest, sender, to, time.Now())
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				filename := filepath.Join(dir, name+".eml")
+				err = ioutil.WriteFile(filename, msg, 0644)
+				if err == nil {
+					fmt.Printf("%s: wrote %s for %s\n", name, filename, strings.Join(to, ", "))
+				}
+			} else {
+				err = smtp.SendMail(server, auth, sender.Address, to, msg)
+				if err == nil {
+					fmt.Printf("%s: sent to %s\n", name, strings.Join(to, ", "))
+				}
+			}
+			if err != nil {
+				fmt.Printf("Unable to email %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			sent++
+		}
+	}
+
+	fmt.Printf("\nEmailed %d repos, %d opted out, %d without leader emails, %d failed\n", sent, optedOut, noLeaders, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d emails could not be sent", failed)
+	}
+
+	return nil
+}
+
+// newDigest lists the repo's findings at or above minSeverity, most severe
+// first
+func newDigest(name string, kind string, status *chapterStatusT, minSeverity StatusLevelT) digestT {
+	digest := digestT{Repo: name, Kind: kind, RepoURL: repoURL(name)}
+
+	for _, f := range status.Findings {
+		if f.Severity < minSeverity {
+			continue
+		}
+
+		df := digestFindingT{Severity: f.Severity, Message: withoutLocalPaths(name, f)}
+		if f.File != "" {
+			df.Link = fileURL(name, f.File, f.Line)
+		}
+		digest.Findings = append(digest.Findings, df)
+
+		if f.Severity == Policy {
+			digest.PolicyFindings++
+		}
+	}
+
+	sort.SliceStable(digest.Findings, func(i, j int) bool {
+		return digest.Findings[i].Severity > digest.Findings[j].Severity
+	})
+
+	return digest
+}
+
+// recipients removes the opted out and repeated addresses
+func recipients(emails []string, optOut map[string]bool) []string {
+	var to []string
+	seen := map[string]bool{}
+	for _, email := range emails {
+		key := strings.ToLower(email)
+		if optOut[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		to = append(to, email)
+	}
+
+	return to
+}
+
+// readOptOut reads the email addresses and repo names in the opt out file,
+// lower cased. Blank lines and # comments are skipped.
+func readOptOut(filename string) (map[string]bool, error) {
+	optOut := map[string]bool{}
+	if filename == "" {
+		return optOut, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			optOut[strings.ToLower(line)] = true
+		}
+	}
+
+	return optOut, scanner.Err()
+}
+
+// renderEmail executes the template and adds the headers every email needs.
+// Lines end with CRLF, as SMTP and .eml files expect.
+func renderEmail(tmpl *template.Template, digest digestT, sender *mail.Address, to []string, when time.Time) ([]byte, error) {
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, digest); err != nil {
+		return nil, err
+	}
+
+	parsed, err := mail.ReadMessage(&rendered)
+	if err != nil {
+		return nil, fmt.Errorf("email template: %v", err)
+	}
+	if parsed.Header.Get("Subject") == "" {
+		return nil, fmt.Errorf("email template: no Subject header")
+	}
+	body, err := ioutil.ReadAll(parsed.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	set := map[string]bool{}
+	header := func(key string, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", key, value)
+		set[textproto.CanonicalMIMEHeaderKey(key)] = true
+	}
+	header("From", sender.String())
+	header("To", strings.Join(to, ", "))
+	header("Date", when.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+
+	var keys []string
+	for key := range parsed.Header {
+		if set[key] {
+			return nil, fmt.Errorf("email template: the %s header is set by notify-email", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range parsed.Header[key] {
+			header(key, mime.QEncoding.Encode("utf-8", value))
+		}
+	}
+
+	msg.WriteString("\r\n")
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		msg.WriteString(strings.TrimRight(line, "\r") + "\r\n")
+	}
+
+	return msg.Bytes(), nil
+}
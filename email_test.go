@@ -0,0 +1,328 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// smtpMessageT is an email received by fakeSMTP
+type smtpMessageT struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPT is an SMTP server that keeps the emails it is sent. Recipients
+// at bounce.example.com are refused.
+type fakeSMTPT struct {
+	addr string
+
+	mu       sync.Mutex
+	messages []smtpMessageT
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTPT {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeSMTPT{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(textproto.NewConn(conn))
+		}
+	}()
+
+	return s
+}
+
+func (s *fakeSMTPT) received() []smtpMessageT {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]smtpMessageT{}, s.messages...)
+}
+
+func (s *fakeSMTPT) serve(conn *textproto.Conn) {
+	defer conn.Close()
+
+	var msg smtpMessageT
+	conn.PrintfLine("220 localhost fake SMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			conn.PrintfLine("250-localhost")
+			conn.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			msg = smtpMessageT{From: addressIn(line)}
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			to := addressIn(line)
+			if strings.HasSuffix(to, "@bounce.example.com") {
+				conn.PrintfLine("550 no such user")
+				continue
+			}
+			msg.To = append(msg.To, to)
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 go ahead")
+			data, err := ioutil.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			conn.PrintfLine("250 OK")
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("250 OK")
+		}
+	}
+}
+
+// addressIn is the address in <> on an SMTP command line
+func addressIn(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return line[start+1 : end]
+}
+
+func testEmailOutput(t *testing.T) string {
+	return writeTestOutput(t, map[string]*chapterStatusT{
+		"www-chapter-london": {
+			LeaderEmails: []string{"jane@owasp.org", "Bob@owasp.org", "jane@owasp.org"},
+			Findings: []Finding{
+				{RuleID: "example-tab", File: "tab_example.md", Severity: Low, Message: "Example tab"},
+				{RuleID: "old-wiki", File: "index.md", Line: 3, Severity: Policy, Message: "Old wiki link"},
+				{RuleID: "meetup-exists", Severity: Info, Message: "Meetup group is active"},
+			},
+		},
+		"www-chapter-paris": {
+			LeaderEmails: []string{"marie@owasp.org"},
+			Findings:     []Finding{{RuleID: "leaders-file", Severity: Policy, Message: "No leaders"}},
+		},
+		"www-chapter-tokyo": {
+			Findings: []Finding{{RuleID: "leaders-file", Severity: Policy, Message: "No leaders"}},
+		},
+		"www-chapter-quiet": {
+			LeaderEmails: []string{"quiet@owasp.org"},
+			Findings:     []Finding{{RuleID: "meetup-exists", Severity: Info, Message: "Meetup group is active"}},
+		},
+		"www-chapter-bounce": {
+			LeaderEmails: []string{"gone@bounce.example.com"},
+			Findings:     []Finding{{RuleID: "leaders-file", Severity: Policy, Message: "No leaders"}},
+		},
+	}, scanInfoT{})
+}
+
+func TestNotifyEmail(t *testing.T) {
+	testConfig(t)
+	smtpServer := newFakeSMTP(t)
+
+	optOut := filepath.Join(t.TempDir(), "opt-out.txt")
+	if err := ioutil.WriteFile(optOut, []byte("# no digests please\nwww-chapter-paris\nbob@OWASP.org  # in person only\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runNotifyEmail([]string{"-input", testEmailOutput(t), "-opt-out", optOut, "-smtp", smtpServer.addr, "-from", "OWASP Policy Scanner <scanner@example.org>"})
+	if err == nil || err.Error() != "1 emails could not be sent" {
+		t.Errorf("err = %v, want the bounce to fail", err)
+	}
+
+	received := smtpServer.received()
+	if len(received) != 1 {
+		t.Fatalf("%d emails sent, want only london's: %+v", len(received), received)
+	}
+	sent := received[0]
+	if sent.From != "scanner@example.org" {
+		t.Errorf("sent from %q", sent.From)
+	}
+	assertStrings(t, "recipients", sent.To, []string{"jane@owasp.org"})
+
+	msg, err := mail.ReadMessage(strings.NewReader(sent.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeaders := map[string]string{
+		"From":    `"OWASP Policy Scanner" <scanner@example.org>`,
+		"To":      "jane@owasp.org",
+		"Subject": "OWASP policy scanner findings for www-chapter-london",
+	}
+	for key, want := range wantHeaders {
+		if got := msg.Header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	body, err := ioutil.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(body)
+	policy := strings.Index(text, "- Policy: Old wiki link\n  https://github.com/OWASP/www-chapter-london/blob/HEAD/index.md#L3\n")
+	low := strings.Index(text, "- Low: Example tab\n")
+	if policy < 0 || low < policy {
+		t.Errorf("body doesn't list the policy violation before the low finding:\n%s", text)
+	}
+	if strings.Contains(text, "Meetup group is active") {
+		t.Errorf("body lists an Info finding below -severity low:\n%s", text)
+	}
+}
+
+func TestNotifyEmailDryRun(t *testing.T) {
+	testConfig(t)
+	dir := filepath.Join(t.TempDir(), "emails")
+
+	// nothing listens on the -smtp address
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused := l.Addr().String()
+	l.Close()
+
+	args := []string{"-input", testEmailOutput(t), "-dry-run", "-dir", dir, "-smtp", unused, "-from", "scanner@example.org", "-severity", "policy", "-chapter", "www-chapter-paris"}
+	if err := runNotifyEmail(args); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStrings(t, "files", files, []string{filepath.Join(dir, "www-chapter-paris.eml")})
+	if data := readFile(t, files[0]); !strings.Contains(data, "To: marie@owasp.org\r\n") {
+		t.Errorf("www-chapter-paris.eml = %q", data)
+	}
+}
+
+func TestRenderEmail(t *testing.T) {
+	digest := digestT{Repo: "www-chapter-london", Findings: []digestFindingT{{Severity: Policy, Message: "Old wiki link"}}}
+	sender := &mail.Address{Name: "Scanner", Address: "scanner@example.org"}
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "headers and CRLF line endings",
+			tmpl: "Subject: Findings for {{.Repo}}\nReply-To: help@example.org\n\n{{range .Findings}}{{.Severity}}: {{.Message}}\n{{end}}",
+			want: "From: \"Scanner\" <scanner@example.org>\r\nTo: jane@owasp.org, bob@owasp.org\r\nDate: Fri, 01 Mar 2024 12:00:00 +0000\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n" +
+				"Reply-To: help@example.org\r\nSubject: Findings for www-chapter-london\r\n\r\nPolicy: Old wiki link\r\n",
+		},
+		{
+			name: "a subject that isn't ASCII",
+			tmpl: "Subject: Résultats\n\nBonjour\n",
+			want: "From: \"Scanner\" <scanner@example.org>\r\nTo: jane@owasp.org, bob@owasp.org\r\nDate: Fri, 01 Mar 2024 12:00:00 +0000\r\n" +
+				"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n" +
+				"Subject: =?utf-8?q?R=C3=A9sultats?=\r\n\r\nBonjour\r\n",
+		},
+		{
+			name:    "no subject",
+			tmpl:    "Reply-To: help@example.org\n\nHello\n",
+			wantErr: "no Subject header",
+		},
+		{
+			name:    "a header notify-email sets",
+			tmpl:    "Subject: Hi\nTo: everyone@example.org\n\nHello\n",
+			wantErr: "the To header is set by notify-email",
+		},
+		{
+			name:    "no headers",
+			tmpl:    "Hello",
+			wantErr: "email template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("email").Parse(tt.tmpl))
+			msg, err := renderEmail(tmpl, digest, sender, []string{"jane@owasp.org", "bob@owasp.org"}, when)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(msg) != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutLocalPaths(t *testing.T) {
+	tests := []struct {
+		f    Finding
+		want string
+	}{
+		{Finding{File: "index.md", Message: "Old wiki link found in chapters/www-chapter-london/index.md on line 3"}, "Old wiki link found in index.md on line 3"},
+		{Finding{File: "_site", Message: "Site directory is present at /home/scanner/chapters/www-chapter-london/_site"}, "Site directory is present at _site"},
+		{Finding{File: "tab_example.md", Message: "Example tab found at: www-chapter-london/tab_example.md"}, "Example tab found at: tab_example.md"},
+		// scanned with -path from a directory named otherwise
+		{Finding{File: "info.md", Message: "Default text present in /tmp/checkout/info.md on line 2"}, "Default text present in info.md on line 2"},
+		{Finding{Message: "Gem jekyll is missing from chapters/www-chapter-london/Gemfile"}, "Gem jekyll is missing from Gemfile"},
+		{Finding{File: "index.md", Message: "Link to https://owasp.org/www-chapter-london/index.md is broken"}, "Link to https://owasp.org/www-chapter-london/index.md is broken"},
+		{Finding{Message: "www-chapter-london has 1 leaders"}, "www-chapter-london has 1 leaders"},
+	}
+
+	for _, tt := range tests {
+		if got := withoutLocalPaths("www-chapter-london", tt.f); got != tt.want {
+			t.Errorf("withoutLocalPaths(%q) = %q, want %q", tt.f.Message, got, tt.want)
+		}
+	}
+}
+
+func TestNewDigest(t *testing.T) {
+	status := &chapterStatusT{Findings: []Finding{
+		{RuleID: "example-tab", File: "tab_example.md", Severity: Low, Message: "Example tab found at: chapters/www-chapter-london/tab_example.md"},
+		{RuleID: "old-wiki", File: "index.md", Line: 3, Severity: Policy, Message: "Old wiki link found in chapters/www-chapter-london/index.md on line 3"},
+		{RuleID: "meetup-exists", Severity: Info, Message: "Meetup group is active"},
+	}}
+
+	digest := newDigest("www-chapter-london", "chapter", status, Low)
+
+	want := []digestFindingT{
+		{Severity: Policy, Message: "Old wiki link found in index.md on line 3", Link: fileURL("www-chapter-london", "index.md", 3)},
+		{Severity: Low, Message: "Example tab found at: tab_example.md", Link: fileURL("www-chapter-london", "tab_example.md", 0)},
+	}
+	if !reflect.DeepEqual(digest.Findings, want) || digest.PolicyFindings != 1 {
+		t.Errorf("digest = %+v, want findings %+v and 1 policy finding", digest, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Finding is a single problem reported by a check. File is relative to the
+// chapter repo so downstream tooling can link straight to the offending line.
+type Finding struct {
+	RuleID   string
+	Chapter  string
+	File     string
+	Line     int
+	Column   int
+	Severity StatusLevelT
+	Message  string
+	Snippet  string
+
+	// stable ID used by -baseline, see fingerprint()
+	Fingerprint string
+}
+
+func (sl StatusLevelT) MarshalText() ([]byte, error) {
+	return []byte(sl.String()), nil
+}
+
+func (sl *StatusLevelT) UnmarshalText(text []byte) error {
+	level, err := parseStatusLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*sl = level
+
+	return nil
+}
+
+func parseStatusLevel(s string) (StatusLevelT, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return Info, nil
+	case "low":
+		return Low, nil
+	case "medium":
+		return Medium, nil
+	case "high":
+		return High, nil
+	case "policy":
+		return Policy, nil
+	}
+
+	return Info, fmt.Errorf("unknown severity %q", s)
+}
+
+func repoRelative(root string, filename string) string {
+	if filename == "" || root == "" {
+		return filename
+	}
+
+	rel, err := filepath.Rel(root, filename)
+	if err != nil {
+		return filename
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// repoRelativeMessage makes the paths in a message, which name files by where
+// the repo was scanned, e.g. chapters/www-chapter-london/index.md, relative to
+// the repo like File is
+func repoRelativeMessage(root string, message string) string {
+	root = filepath.Clean(root)
+	if root == "." {
+		return message
+	}
+
+	return strings.Replace(message, root+string(filepath.Separator), "", -1)
+}
+
+var messageToken = regexp.MustCompile(`[^\s()<>\[\]"']+`)
+
+// withoutLocalPaths returns the message with the paths it names files by made
+// relative to the repo, for readers who don't have the scanner's checkout.
+// Output doesn't record where the repo was scanned, so the paths are found by
+// the repo's directory, or by ending in the finding's File. Links are kept.
+func withoutLocalPaths(repo string, f Finding) string {
+	return messageToken.ReplaceAllStringFunc(f.Message, func(token string) string {
+		if strings.Contains(token, "://") {
+			return token
+		}
+		if i := strings.LastIndex(token, "/"+repo+"/"); i >= 0 {
+			return token[i+len(repo)+2:]
+		}
+		if strings.HasPrefix(token, repo+"/") {
+			return token[len(repo)+1:]
+		}
+		if f.File != "" && strings.HasSuffix(token, "/"+f.File) {
+			return f.File
+		}
+
+		return token
+	})
+}
+
+// column returns the 1 based column of substr in text, or 0 if it isn't present
+func column(text string, substr string) int {
+	i := strings.Index(text, substr)
+	if i < 0 {
+		return 0
+	}
+
+	return utf8.RuneCountInString(text[:i]) + 1
+}
+
+// reportFinding prints the finding and records it against the chapter,
+// unless it is in the -baseline
+func (c *chapterScanT) reportFinding(f Finding) {
+	f.Chapter = c.name
+	f.File = repoRelative(c.path, f.File)
+	f.Snippet = strings.TrimSpace(f.Snippet)
+	c.fingerprintFinding(&f)
+
+	if baseline[f.Fingerprint] {
+		c.baselined = append(c.baselined, f)
+		return
+	}
+
+	c.printStatus(f.Severity, f.Message)
+
+	c.status.Findings = append(c.status.Findings, f)
+}
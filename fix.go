@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Fixer is implemented by checks that can fix what they find. With -fix or
+// -dry-run, Fix is called after Run for each entry the check matched. Fixes
+// are made with c.fixFile and c.removeFile, and written once the whole repo
+// has been scanned. Fixable reports whether the check has a fix at all.
+type Fixer interface {
+	Fixable() bool
+	Fix(c *chapterScanT, path string, d fs.DirEntry) error
+}
+
+// fileFixT is the fixed content of a file, nil if the file is to be removed
+type fileFixT struct {
+	path   string
+	before []byte
+	after  []byte
+	rules  []string
+}
+
+// fixedContent returns the file as it is after any earlier fixes, so several
+// fixers can edit the same file
+func (c *chapterScanT) fixedContent(path string) ([]byte, error) {
+	if fix, ok := c.fixes[path]; ok {
+		return fix.after, nil
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+func (c *chapterScanT) fixFile(ruleID string, path string, after []byte) error {
+	fix, ok := c.fixes[path]
+	if !ok {
+		before, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if c.fixes == nil {
+			c.fixes = map[string]*fileFixT{}
+		}
+		fix = &fileFixT{path: path, before: before}
+		c.fixes[path] = fix
+		c.fixOrder = append(c.fixOrder, path)
+	}
+
+	fix.after = after
+	if !contains(fix.rules, ruleID) {
+		fix.rules = append(fix.rules, ruleID)
+	}
+
+	return nil
+}
+
+func (c *chapterScanT) removeFile(ruleID string, path string) error {
+	return c.fixFile(ruleID, path, nil)
+}
+
+// applyFixes writes the fixes to the repo, or with -dry-run prints them as a
+// unified diff
+func (c *chapterScanT) applyFixes() error {
+	for _, path := range c.fixOrder {
+		fix := c.fixes[path]
+		if fix.after != nil && bytes.Equal(fix.before, fix.after) {
+			continue
+		}
+
+		name := repoRelative(c.path, path)
+
+		if config.dryRun {
+			unifiedDiff(&c.out, name, fix.before, fix.after)
+			continue
+		}
+
+		var err error
+		if fix.after == nil {
+			err = os.Remove(path)
+		} else {
+			err = ioutil.WriteFile(path, fix.after, 0644)
+		}
+		if err != nil {
+			return err
+		}
+
+		action := "Fixed"
+		if fix.after == nil {
+			action = "Removed"
+		}
+		c.printStatus(Info, fmt.Sprintf("%s %s (%s)", action, name, strings.Join(fix.rules, ", ")))
+		c.status.Fixed = append(c.status.Fixed, name)
+	}
+
+	return nil
+}
+
+// fixLines applies fix to each line of the file, keeping the line endings
+func (c *chapterScanT) fixLines(ruleID string, path string, fix func(line string) string) error {
+	data, err := c.fixedContent(path)
+	if err != nil || data == nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	changed := false
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\r\n")
+		fixed := fix(text)
+		if fixed != text {
+			lines[i] = fixed + line[len(text):]
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return c.fixFile(ruleID, path, []byte(strings.Join(lines, "")))
+}
+
+// oldWikiPages maps the old wiki pages that have a new home on owasp.org to
+// it, by page name in lower case
+var oldWikiPages = map[string]string{
+	"main_page":                                "https://owasp.org/",
+	"about_owasp":                              "https://owasp.org/about/",
+	"membership":                               "https://owasp.org/membership/",
+	"corporate_membership":                     "https://owasp.org/supporters/",
+	"owasp_chapter":                            "https://owasp.org/chapters/",
+	"category:owasp_chapter":                   "https://owasp.org/chapters/",
+	"category:owasp_project":                   "https://owasp.org/projects/",
+	"owasp_project_inventory":                  "https://owasp.org/projects/",
+	"category:owasp_events":                    "https://owasp.org/events/",
+	"owasp_top_ten_project":                    "https://owasp.org/www-project-top-ten/",
+	"category:owasp_top_ten_project":           "https://owasp.org/www-project-top-ten/",
+	"owasp_zed_attack_proxy_project":           "https://owasp.org/www-project-zap/",
+	"owasp_juice_shop_project":                 "https://owasp.org/www-project-juice-shop/",
+	"owasp_dependency_check":                   "https://owasp.org/www-project-dependency-check/",
+	"owasp_cheat_sheet_series":                 "https://cheatsheetseries.owasp.org/",
+	"owasp_testing_project":                    "https://owasp.org/www-project-web-security-testing-guide/",
+	"owasp_testing_guide_v4_table_of_contents": "https://owasp.org/www-project-web-security-testing-guide/",
+	"category:owasp_application_security_verification_standard_project": "https://owasp.org/www-project-application-security-verification-standard/",
+}
+
+// an old wiki link, with the page name as the first group
+var oldWikiLink = regexp.MustCompile(`(?:https?://)?www\.owasp\.org/index\.php(?:/|\?title=)([^\s)\]"'<>#?&]+)(?:[#?&][^\s)\]"'<>]*)?`)
+
+// Old wiki links to pages in oldWikiPages are changed to their new home.
+// Other pages were archived, and are left for the leaders to update by hand.
+func fixOldWiki(c *chapterScanT, filename string, d fs.DirEntry) error {
+	return c.fixLines("old-wiki", filename, func(line string) string {
+		return oldWikiLink.ReplaceAllStringFunc(line, func(link string) string {
+			page := oldWikiLink.FindStringSubmatch(link)[1]
+			if unescaped, err := url.PathUnescape(page); err == nil {
+				page = unescaped
+			}
+			page = strings.ToLower(strings.ReplaceAll(page, " ", "_"))
+
+			if newURL, ok := oldWikiPages[page]; ok {
+				return newURL
+			}
+			return link
+		})
+	})
+}
+
+func fixDefaultExampleTab(c *chapterScanT, filename string, d fs.DirEntry) error {
+	return c.removeFile("example-tab", filename)
+}
+
+// Jekyll's output and the lock file shouldn't be committed
+func fixOldGitIgnore(c *chapterScanT, filename string, d fs.DirEntry) error {
+	data, err := c.fixedContent(filename)
+	if err != nil || data == nil {
+		return err
+	}
+
+	fixed := string(data)
+	for _, ignore := range []string{"_site", "Gemfile.lock"} {
+		if strings.Contains(fixed, ignore) {
+			continue
+		}
+		if fixed != "" && !strings.HasSuffix(fixed, "\n") {
+			fixed += "\n"
+		}
+		fixed += ignore + "\n"
+	}
+
+	return c.fixFile("old-gitignore", filename, []byte(fixed))
+}
+
+func fixDefaultMigrationHeader(c *chapterScanT, filename string, d fs.DirEntry) error {
+	data, err := c.fixedContent(filename)
+	if err != nil || data == nil {
+		return err
+	}
+
+	var kept []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.TrimSpace(line) != "auto-migrated: 1" {
+			kept = append(kept, line)
+		}
+	}
+
+	return c.fixFile("migration-header", filename, []byte(strings.Join(kept, "")))
+}
+
+// unifiedDiff writes the change from before to after in unified diff format,
+// with 3 lines of context. after is nil for a removed file.
+func unifiedDiff(w io.Writer, name string, before []byte, after []byte) {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	fmt.Fprintf(w, "--- a/%s\n", name)
+	if after == nil {
+		fmt.Fprintln(w, "+++ /dev/null")
+	} else {
+		fmt.Fprintf(w, "+++ b/%s\n", name)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type opT struct {
+		kind byte // ' ', '-' or '+'
+		text string
+		i, j int // line in a and b before this op
+	}
+	var ops []opT
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, opT{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, opT{'+', b[j], i, j})
+			j++
+		default:
+			ops = append(ops, opT{'-', a[i], i, j})
+			i++
+		}
+	}
+
+	const context = 3
+	for start := 0; start < len(ops); {
+		// find the next change, and the end of the hunk around it
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*context {
+				break
+			}
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := end + context
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		var aLines, bLines int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aLines++
+			}
+			if op.kind != '-' {
+				bLines++
+			}
+		}
+
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(ops[from].i, aLines), hunkRange(ops[from].j, bLines))
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(w, "%c%s", op.kind, op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				fmt.Fprint(w, "\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = to
+	}
+}
+
+func hunkRange(start int, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
+
+// splitLines keeps the newlines, so a missing one at the end of the file shows
+// up as a change
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// letterLines is lines from to to, each a letter three times: aaa, bbb, ...
+func letterLines(from int, to int) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		b.WriteString(strings.Repeat(string(rune('a'+i%26)), 3) + "\n")
+	}
+
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  *string
+		want   string
+	}{
+		{
+			name:   "no change",
+			before: "a\nb\n",
+			after:  strPtr("a\nb\n"),
+			want:   "--- a/f.md\n+++ b/f.md\n",
+		},
+		{
+			name:   "a changed line with context",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:  strPtr("1\n2\n3\n4\nfive\n6\n7\n8\n9\n"),
+			want: "--- a/f.md\n+++ b/f.md\n" +
+				"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:   "a line added at the start",
+			before: "a\nb\n",
+			after:  strPtr("new\na\nb\n"),
+			want:   "--- a/f.md\n+++ b/f.md\n@@ -1,2 +1,3 @@\n+new\n a\n b\n",
+		},
+		{
+			name:   "a new file",
+			before: "",
+			after:  strPtr("a\n"),
+			want:   "--- a/f.md\n+++ b/f.md\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name:   "a removed file",
+			before: "a\nb\n",
+			want:   "--- a/f.md\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:   "a newline added at the end",
+			before: "a\nb",
+			after:  strPtr("a\nb\n"),
+			want:   "--- a/f.md\n+++ b/f.md\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:   "changes close together share a hunk",
+			before: letterLines(0, 11),
+			after:  strPtr(strings.Replace(strings.Replace(letterLines(0, 11), "bbb", "BBB", 1), "iii", "III", 1)),
+			want: "--- a/f.md\n+++ b/f.md\n" +
+				"@@ -1,12 +1,12 @@\n aaa\n-bbb\n+BBB\n ccc\n ddd\n eee\n fff\n ggg\n hhh\n-iii\n+III\n jjj\n kkk\n lll\n",
+		},
+		{
+			name:   "changes far apart have their own hunks",
+			before: letterLines(0, 11),
+			after:  strPtr(strings.Replace(strings.Replace(letterLines(0, 11), "bbb", "BBB", 1), "jjj", "JJJ", 1)),
+			want: "--- a/f.md\n+++ b/f.md\n" +
+				"@@ -1,5 +1,5 @@\n aaa\n-bbb\n+BBB\n ccc\n ddd\n eee\n" +
+				"@@ -7,6 +7,6 @@\n ggg\n hhh\n iii\n-jjj\n+JJJ\n kkk\n lll\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var after []byte
+			if tt.after != nil {
+				after = []byte(*tt.after)
+			}
+
+			var b bytes.Buffer
+			unifiedDiff(&b, "f.md", []byte(tt.before), after)
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestFixOldWiki(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			"See https://www.owasp.org/index.php/Main_Page for more",
+			"See https://owasp.org/ for more",
+		},
+		{
+			"[Chapters](http://www.owasp.org/index.php/Category:OWASP_Chapter)",
+			"[Chapters](https://owasp.org/chapters/)",
+		},
+		{
+			"[Top 10](https://www.owasp.org/index.php?title=OWASP_Top_Ten_Project&action=edit)",
+			"[Top 10](https://owasp.org/www-project-top-ten/)",
+		},
+		{
+			"<https://www.owasp.org/index.php/OWASP%20Zed%20Attack%20Proxy%20Project#tab=Main>",
+			"<https://owasp.org/www-project-zap/>",
+		},
+		{
+			"www.owasp.org/index.php/Membership and www.owasp.org/index.php/Some_Old_Page",
+			"https://owasp.org/membership/ and www.owasp.org/index.php/Some_Old_Page",
+		},
+		{
+			"[London](https://www.owasp.org/index.php/London)",
+			"[London](https://www.owasp.org/index.php/London)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			testConfig(t)
+			dir := t.TempDir()
+			path := filepath.Join(dir, "index.md")
+			if err := ioutil.WriteFile(path, []byte(tt.line+"\r\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := newTestScan("www-chapter-london", dir)
+			if err := fixOldWiki(c, path, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.fixedContent(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want+"\r\n" {
+				t.Errorf("fixed = %q, want %q", got, tt.want+"\r\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterT is the YAML front matter between the --- lines at the top of a
+// Jekyll page
+type frontMatterT struct {
+	root *yaml.Node
+}
+
+// parseFrontMatter returns nil if the file has no front matter
+func parseFrontMatter(filename string) (*frontMatterT, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) == 0 || string(bytes.TrimSpace(lines[0])) != "---" {
+		return nil, nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimSpace(lines[i])) == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("front matter in %s is not closed with ---", filename)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bytes.Join(lines[1:end], nil), &doc); err != nil {
+		return nil, fmt.Errorf("front matter in %s: %v", filename, err)
+	}
+
+	fm := &frontMatterT{root: &yaml.Node{Kind: yaml.MappingNode}}
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		fm.root = doc.Content[0]
+	}
+
+	return fm, nil
+}
+
+// get returns the value of key, and the line of the file it is on. The line
+// is 1 (the opening ---) if the key isn't present.
+func (fm *frontMatterT) get(key string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(fm.root.Content); i += 2 {
+		if fm.root.Content[i].Value == key {
+			// yaml counts from the line after the opening ---
+			return fm.root.Content[i+1], fm.root.Content[i].Line + 1
+		}
+	}
+
+	return nil, 1
+}
+
+// list returns the value of key as a list, whether it was written as a
+// single value or a sequence
+func (fm *frontMatterT) list(key string) []string {
+	value, _ := fm.get(key)
+	if value == nil {
+		return nil
+	}
+
+	if value.Kind == yaml.SequenceNode {
+		var items []string
+		for _, item := range value.Content {
+			items = append(items, item.Value)
+		}
+		return items
+	}
+
+	if value.Value == "" {
+		return nil
+	}
+
+	return []string{value.Value}
+}
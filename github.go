@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// githubRepoT is the part of a GitHub repository we use
+// https://docs.github.com/en/rest/repos/repos#list-organization-repositories
+type githubRepoT struct {
+	Name           string `json:"name"`
+	Default_branch string `json:"default_branch"`
+	Archived       bool   `json:"archived"`
+	Has_pages      bool   `json:"has_pages"`
+}
+
+var nextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// githubGet fetches reqUrl from the GitHub API into out. reqUrl is relative to
+// -githuburl unless it is absolute, as the pagination links are. It returns
+// the next page, if there is one, and the response status so callers can
+// treat 404s as answers rather than errors.
+func githubGet(reqUrl string, out interface{}) (next string, status int, err error) {
+	return githubDo("GET", reqUrl, nil, out)
+}
+
+// githubDo sends in, if not nil, as the JSON body of the request, and reads
+// the response into out, if not nil
+func githubDo(method string, reqUrl string, in interface{}, out interface{}) (next string, status int, err error) {
+	if !strings.HasPrefix(reqUrl, "http://") && !strings.HasPrefix(reqUrl, "https://") {
+		reqUrl = strings.TrimSuffix(config.githubURL, "/") + reqUrl
+	}
+
+	var reqBody io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return "", 0, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, reqUrl, reqBody)
+	if err != nil {
+		return "", 0, err
+	}
+	if config.githubkey != "" {
+		req.Header.Set("Authorization", "token "+config.githubkey)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	// Sleep if necessary to slow things down
+	requestsLeft, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err == nil && requestsLeft < 1 {
+		requestsTimeOut, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		resetTime := requestsTimeOut - time.Now().Unix()
+		if resetTime > 0 {
+			fmt.Printf("GitHub API limit reached, sleeping for %d seconds\n", resetTime)
+			time.Sleep(time.Duration(resetTime) * time.Second)
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", resp.StatusCode, fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp.StatusCode, err
+	}
+
+	if m := nextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		next = m[1]
+	}
+
+	if out == nil {
+		return next, resp.StatusCode, nil
+	}
+
+	return next, resp.StatusCode, json.Unmarshal(body, out)
+}
+
+// listOrgRepos lists every repo in the org, following the Link headers
+// rather than assuming how many pages there are
+func listOrgRepos(org string) ([]githubRepoT, error) {
+	var repos []githubRepoT
+
+	next := fmt.Sprintf("/orgs/%s/repos?type=public&per_page=100", org)
+	for next != "" {
+		var page []githubRepoT
+		var err error
+		next, _, err = githubGet(next, &page)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, page...)
+	}
+
+	return repos, nil
+}
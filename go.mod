@@ -2,4 +2,4 @@ module owasp.org/scanner/v2
 
 go 1.16
 
-
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyRunT is one scan in the history file. Each run is a line of JSON
+// appended to the file, so the history is never rewritten.
+type historyRunT struct {
+	Time  time.Time
+	Repos map[string]historyRepoT
+}
+
+// historyRepoT is the part of chapterStatusT worth following over time
+type historyRepoT struct {
+	Kind                   string
+	Leaders                int
+	MeetupPastMeetings     int
+	MeetupUpcomingMeetings int
+	PolicyFindings         int
+	Findings               int
+}
+
+// appendHistory records the scan in the history file. Findings suppressed by
+// -baseline still count, as they are still there.
+func appendHistory(scans []*chapterScanT, when time.Time, filename string) error {
+	run := historyRunT{Time: when.UTC(), Repos: map[string]historyRepoT{}}
+
+	for _, c := range scans {
+		repo := historyRepoT{
+			Kind:                   c.kind,
+			Leaders:                c.status.Leaders,
+			MeetupPastMeetings:     c.status.MeetupPastMeetings,
+			MeetupUpcomingMeetings: c.status.MeetupUpcomingMeetings,
+		}
+
+		findings := append(append([]Finding{}, c.status.Findings...), c.baselined...)
+		for _, f := range findings {
+			repo.Findings++
+			if f.Severity == Policy {
+				repo.PolicyFindings++
+			}
+		}
+
+		run.Repos[c.name] = repo
+	}
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// readHistory returns the runs in the history file, oldest first
+func readHistory(filename string) ([]historyRunT, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []historyRunT
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var run historyRunT
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", filename, line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+
+	return runs, nil
+}
+
+// runHistory shows how each repo has changed over the recorded scans
+func runHistory(args []string) error {
+	last := 10
+	history := "scanner_history.jsonl"
+
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	fs.StringVar(&history, "history", history, "History file written by the scans with -history")
+	fs.Var(&config.chapters, "chapter", "Only show the repo with this name, can be given more than once")
+	fs.StringVar(&config.kinds, "kinds", config.kinds, "Comma separated list of repo kinds to show: chapter, project, committee, event (default all)")
+	fs.IntVar(&last, "runs", last, "Number of most recent scans to show for each repo, 0 for all")
+	fs.Parse(args)
+
+	kinds, err := selectedKinds()
+	if err != nil {
+		return err
+	}
+
+	runs, err := readHistory(history)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no scans in %s", history)
+	}
+
+	writeHistory(os.Stdout, runs, kinds, last, time.Now())
+	return nil
+}
+
+func writeHistory(w io.Writer, runs []historyRunT, kinds []repoKindT, last int, now time.Time) {
+	names := map[string]bool{}
+	for _, run := range runs {
+		for name, repo := range run.Repos {
+			if containsKind(kinds, repo.Kind) && selectedRepo(name) {
+				names[name] = true
+			}
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		var seen []historyRunT
+		for _, run := range runs {
+			if _, ok := run.Repos[name]; ok {
+				seen = append(seen, run)
+			}
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, name)
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "  SCANNED\tLEADERS\tMEETUP PAST\tMEETUP UPCOMING\tPOLICY\tFINDINGS")
+		shown := seen
+		if last > 0 && len(shown) > last {
+			shown = shown[len(shown)-last:]
+		}
+		for _, run := range shown {
+			r := run.Repos[name]
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\t%d\n", run.Time.Local().Format("2006-01-02 15:04"),
+				r.Leaders, r.MeetupPastMeetings, r.MeetupUpcomingMeetings, r.PolicyFindings, r.Findings)
+		}
+		tw.Flush()
+
+		fmt.Fprintln(w, "  "+lastCompliant(name, seen, now))
+	}
+}
+
+// lastCompliant describes when the repo last had no policy findings
+func lastCompliant(name string, seen []historyRunT, now time.Time) string {
+	latest := seen[len(seen)-1]
+	if latest.Repos[name].PolicyFindings == 0 {
+		return "Compliant"
+	}
+
+	for i := len(seen) - 1; i >= 0; i-- {
+		if seen[i].Repos[name].PolicyFindings == 0 {
+			days := int(now.Sub(seen[i].Time).Hours() / 24)
+			return fmt.Sprintf("Last compliant %s (%d days ago)", seen[i].Time.Local().Format("2006-01-02"), days)
+		}
+	}
+
+	return fmt.Sprintf("Not compliant since the first scan on %s", seen[0].Time.Local().Format("2006-01-02"))
+}
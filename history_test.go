@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendHistory(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.jsonl")
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(7 * 24 * time.Hour)
+
+	london := newTestScan("www-chapter-london", "")
+	london.status.Leaders = 2
+	london.status.MeetupPastMeetings = 20
+	london.status.MeetupUpcomingMeetings = 1
+	london.status.Findings = []Finding{{Severity: Policy}, {Severity: Low}}
+	// findings suppressed by -baseline are still there
+	london.baselined = []Finding{{Severity: Policy}}
+	zap := newTestScan("www-project-zap", "")
+	zap.status.Leaders = 3
+
+	// appended out of order, as the times are when each scan started
+	if err := appendHistory([]*chapterScanT{london, zap}, second, filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendHistory([]*chapterScanT{zap}, first.In(time.FixedZone("CET", 3600)), filename); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := readHistory(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []historyRunT{
+		{Time: first, Repos: map[string]historyRepoT{
+			"www-project-zap": {Kind: "project", Leaders: 3},
+		}},
+		{Time: second, Repos: map[string]historyRepoT{
+			"www-chapter-london": {Kind: "chapter", Leaders: 2, MeetupPastMeetings: 20, MeetupUpcomingMeetings: 1, PolicyFindings: 2, Findings: 3},
+			"www-project-zap":    {Kind: "project", Leaders: 3},
+		}},
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("runs = %+v, want %+v", runs, want)
+	}
+}
+
+func TestLastCompliant(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2024, 3, n, 12, 0, 0, 0, time.UTC) }
+	run := func(n int, policy int) historyRunT {
+		return historyRunT{Time: day(n), Repos: map[string]historyRepoT{"www-chapter-london": {PolicyFindings: policy}}}
+	}
+	date := func(n int) string { return day(n).Local().Format("2006-01-02") }
+	now := day(20)
+
+	tests := []struct {
+		name string
+		seen []historyRunT
+		want string
+	}{
+		{"compliant", []historyRunT{run(1, 2), run(10, 0)}, "Compliant"},
+		{"compliant before", []historyRunT{run(1, 0), run(10, 0), run(15, 1)}, fmt.Sprintf("Last compliant %s (10 days ago)", date(10))},
+		{"compliant once", []historyRunT{run(1, 0), run(5, 1), run(10, 2)}, fmt.Sprintf("Last compliant %s (19 days ago)", date(1))},
+		{"never compliant", []historyRunT{run(1, 1), run(10, 3)}, fmt.Sprintf("Not compliant since the first scan on %s", date(1))},
+		{"one scan", []historyRunT{run(19, 1)}, fmt.Sprintf("Not compliant since the first scan on %s", date(19))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastCompliant("www-chapter-london", tt.seen, now); got != tt.want {
+				t.Errorf("lastCompliant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
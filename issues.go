@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// githubIssueT is the part of a GitHub issue we use
+// https://docs.github.com/en/rest/issues/issues
+type githubIssueT struct {
+	Number       int              `json:"number"`
+	Title        string           `json:"title"`
+	Body         string           `json:"body"`
+	Html_url     string           `json:"html_url"`
+	Pull_request *json.RawMessage `json:"pull_request"`
+}
+
+// issueRequestT creates, edits or comments on an issue. Labels are listed as
+// objects in an issue, but set by name.
+type issueRequestT struct {
+	Title  string   `json:"title,omitempty"`
+	Body   string   `json:"body,omitempty"`
+	State  string   `json:"state,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+const issueTitle = "OWASP policy violations found by the policy scanner"
+
+// the hidden line in the issue body listing the rules its violations were
+// found by, so it is only closed or updated by a scan that ran them
+var issueRulesLine = regexp.MustCompile(`(?m)^<!-- policy-scanner rules: ([^>]*) -->$`)
+
+// runNotifyGitHub keeps a tracking issue, marked with -label, on each repo
+// with policy findings in the -input file. The issue is opened the first time
+// and updated afterwards, and closed when a later scan no longer finds any
+// policy violations. Issues are left alone by scans that didn't run the rules
+// whose violations they list.
+func runNotifyGitHub(args []string) error {
+	input := "scanner_output.json"
+	label := "policy-scanner"
+	dryRun := false
+
+	fs := flag.NewFlagSet("notify-github", flag.ExitOnError)
+	fs.StringVar(&input, "input", input, "scanner_output.json with the findings to report")
+	fs.StringVar(&label, "label", label, "Label that marks the scanner's tracking issue in each repo")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "Print the issues that would be opened, updated or closed, without changing anything")
+	fs.StringVar(&config.githubkey, "githubkey", config.githubkey, "Set a GitHub API access token")
+	fs.StringVar(&config.githubURL, "githuburl", config.githubURL, "GitHub API base URL")
+	fs.StringVar(&config.org, "org", config.org, "GitHub organization the repos belong to")
+	fs.Var(&config.chapters, "chapter", "Only notify the repo with this name, can be given more than once")
+	fs.StringVar(&config.kinds, "kinds", config.kinds, "Comma separated list of repo kinds to notify: chapter, project, committee, event (default all)")
+	fs.Parse(args)
+
+	if config.githubkey == "" && !dryRun {
+		return fmt.Errorf("notify-github needs a -githubkey that can write issues, or -dry-run")
+	}
+
+	kinds, err := selectedKinds()
+	if err != nil {
+		return err
+	}
+
+	output, info, err := readOutput(input)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("%s doesn't record which rules were run, scan again before notifying", input)
+	}
+	if info.Baseline != "" {
+		// the baselined violations would look fixed, and their issues closed
+		return fmt.Errorf("%s was scanned with -baseline %s, scan without it before notifying", input, info.Baseline)
+	}
+
+	counts := map[string]int{}
+	failed := 0
+	for _, kind := range kinds {
+		repos := output[kind.section]
+
+		var names []string
+		for name := range repos {
+			if selectedRepo(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			action, err := notifyRepo(name, repos[name], info, label, dryRun)
+			if err != nil {
+				fmt.Printf("Unable to notify %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			counts[action]++
+		}
+	}
+
+	fmt.Printf("\nOpened %d issues, updated %d, closed %d, %d unchanged, %d skipped, %d failed\n",
+		counts["opened"], counts["updated"], counts["closed"], counts["unchanged"], counts["skipped"], failed)
+	if failed > 0 {
+		return fmt.Errorf("%d repos could not be notified", failed)
+	}
+
+	return nil
+}
+
+// notifyRepo opens, updates or closes the repo's tracking issue, returning
+// what it did, or "" if the repo has no issue and nothing to report
+func notifyRepo(name string, status *chapterStatusT, info *scanInfoT, label string, dryRun bool) (string, error) {
+	var violations []Finding
+	for _, f := range status.Findings {
+		if f.Severity == Policy {
+			violations = append(violations, f)
+		}
+	}
+
+	issue, err := findTrackingIssue(name, label)
+	if err != nil {
+		return "", err
+	}
+
+	issues := fmt.Sprintf("/repos/%s/%s/issues", config.org, name)
+	body := issueBody(name, violations, label)
+
+	if issue != nil {
+		var notRun []string
+		for _, id := range issueRules(issue.Body) {
+			if !info.ran(id) {
+				notRun = append(notRun, id)
+			}
+		}
+		if len(notRun) > 0 {
+			fmt.Printf("%s: leaving %s alone, the scan didn't run %s\n", name, issue.Html_url, strings.Join(notRun, ", "))
+			return "skipped", nil
+		}
+	}
+
+	switch {
+	case len(violations) == 0 && issue == nil:
+		return "", nil
+
+	case len(violations) == 0:
+		fmt.Printf("%s: closing %s, the policy violations have been fixed\n", name, issue.Html_url)
+		if dryRun {
+			return "closed", nil
+		}
+
+		comment := issueRequestT{Body: "The policy scanner no longer finds any policy violations, thank you!"}
+		if _, _, err := githubDo("POST", fmt.Sprintf("%s/%d/comments", issues, issue.Number), comment, nil); err != nil {
+			return "", err
+		}
+		if _, _, err := githubDo("PATCH", fmt.Sprintf("%s/%d", issues, issue.Number), issueRequestT{State: "closed"}, nil); err != nil {
+			return "", err
+		}
+		return "closed", nil
+
+	case issue == nil:
+		fmt.Printf("%s: opening an issue for %d policy violations\n", name, len(violations))
+		if dryRun {
+			fmt.Printf("\n%s\n\n%s\n", issueTitle, body)
+			return "opened", nil
+		}
+
+		var created githubIssueT
+		if _, _, err := githubDo("POST", issues, issueRequestT{Title: issueTitle, Body: body, Labels: []string{label}}, &created); err != nil {
+			return "", err
+		}
+		fmt.Printf("%s: opened %s\n", name, created.Html_url)
+		return "opened", nil
+
+	case issue.Title == issueTitle && issue.Body == body:
+		return "unchanged", nil
+
+	default:
+		fmt.Printf("%s: updating %s with %d policy violations\n", name, issue.Html_url, len(violations))
+		if dryRun {
+			fmt.Printf("\n%s\n\n%s\n", issueTitle, body)
+			return "updated", nil
+		}
+
+		if _, _, err := githubDo("PATCH", fmt.Sprintf("%s/%d", issues, issue.Number), issueRequestT{Title: issueTitle, Body: body}, nil); err != nil {
+			return "", err
+		}
+		return "updated", nil
+	}
+}
+
+// findTrackingIssue returns the repo's open issue with the label, or nil if
+// there isn't one. Pull requests are listed as issues too, so are skipped.
+func findTrackingIssue(name string, label string) (*githubIssueT, error) {
+	next := fmt.Sprintf("/repos/%s/%s/issues?state=open&labels=%s&per_page=100", config.org, name, url.QueryEscape(label))
+	for next != "" {
+		var page []githubIssueT
+		var err error
+		next, _, err = githubGet(next, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range page {
+			if page[i].Pull_request == nil {
+				return &page[i], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// issueRules returns the rules listed in the issue body. Issues opened before
+// the rules were listed could have been opened by any rule.
+func issueRules(body string) []string {
+	m := issueRulesLine.FindStringSubmatch(body)
+	if m == nil {
+		var ids []string
+		for _, c := range registry {
+			ids = append(ids, c.ID())
+		}
+		return ids
+	}
+
+	if strings.TrimSpace(m[1]) == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(m[1]), ",")
+}
+
+// issueBody lists the violations, linking each to its file and line. It
+// doesn't change unless the violations do, so unchanged issues aren't edited.
+func issueBody(name string, violations []Finding, label string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The OWASP policy scanner found %d policy violations in this repository:\n\n", len(violations))
+	for _, f := range violations {
+		msg := withoutLocalPaths(name, f)
+		switch {
+		case f.File == "":
+			fmt.Fprintf(&b, "- %s\n", msg)
+		case f.Line > 0:
+			fmt.Fprintf(&b, "- [%s line %d](%s): %s\n", f.File, f.Line, fileURL(name, f.File, f.Line), msg)
+		default:
+			fmt.Fprintf(&b, "- [%s](%s): %s\n", f.File, fileURL(name, f.File, f.Line), msg)
+		}
+	}
+	fmt.Fprintf(&b, "\nThe scanner updates this issue each time it runs, and closes it once the violations are fixed. Please leave the `%s` label on it.\n", label)
+
+	var rules []string
+	for _, f := range violations {
+		rules = appendOnce(rules, f.RuleID)
+	}
+	sort.Strings(rules)
+	fmt.Fprintf(&b, "\n<!-- policy-scanner rules: %s -->\n", strings.Join(rules, ","))
+
+	return b.String()
+}
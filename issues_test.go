@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeIssueT is an issue or pull request held by fakeGitHubIssues
+type fakeIssueT struct {
+	githubIssueT
+	Labels   []string
+	State    string
+	Comments []string
+}
+
+// fakeGitHubIssuesT is a GitHub API with the issues of the repos in the OWASP
+// org
+type fakeGitHubIssuesT struct {
+	mu     sync.Mutex
+	repos  map[string][]*fakeIssueT
+	writes int
+}
+
+func (gh *fakeGitHubIssuesT) add(repo string, issue fakeIssueT) *fakeIssueT {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	return gh.insert(repo, &issue)
+}
+
+// insert numbers a new open issue, gh.mu must be held
+func (gh *fakeGitHubIssuesT) insert(repo string, issue *fakeIssueT) *fakeIssueT {
+	issue.State = "open"
+	issue.Number = len(gh.repos[repo]) + 1
+	issue.Html_url = fmt.Sprintf("https://github.com/OWASP/%s/issues/%d", repo, issue.Number)
+	gh.repos[repo] = append(gh.repos[repo], issue)
+
+	return issue
+}
+
+func newFakeGitHubIssues(t *testing.T) (*fakeGitHubIssuesT, string) {
+	gh := &fakeGitHubIssuesT{repos: map[string][]*fakeIssueT{}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// /repos/OWASP/{repo}/issues[/{number}[/comments]]
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 4 || parts[0] != "repos" || parts[1] != "OWASP" || parts[3] != "issues" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		repo := parts[2]
+
+		gh.mu.Lock()
+		defer gh.mu.Unlock()
+
+		if len(parts) == 4 && r.Method == "GET" {
+			if r.URL.Query().Get("state") != "open" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			list := []githubIssueT{}
+			for _, issue := range gh.repos[repo] {
+				if issue.State == "open" && contains(issue.Labels, r.URL.Query().Get("labels")) {
+					list = append(list, issue.githubIssueT)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+
+		var req issueRequestT
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gh.writes++
+
+		if len(parts) == 4 && r.Method == "POST" {
+			issue := gh.insert(repo, &fakeIssueT{githubIssueT: githubIssueT{Title: req.Title, Body: req.Body}, Labels: req.Labels})
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(issue.githubIssueT)
+			return
+		}
+
+		n, err := strconv.Atoi(parts[4])
+		if err != nil || n < 1 || n > len(gh.repos[repo]) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		issue := gh.repos[repo][n-1]
+
+		switch {
+		case len(parts) == 5 && r.Method == "PATCH":
+			if req.Title != "" {
+				issue.Title = req.Title
+			}
+			if req.Body != "" {
+				issue.Body = req.Body
+			}
+			if req.State != "" {
+				issue.State = req.State
+			}
+			json.NewEncoder(w).Encode(issue.githubIssueT)
+		case len(parts) == 6 && parts[5] == "comments" && r.Method == "POST":
+			issue.Comments = append(issue.Comments, req.Body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "{}")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return gh, server.URL
+}
+
+// writeTestOutput writes a scanner_output.json of the chapters, scanned with
+// the rules and baseline in info
+func writeTestOutput(t *testing.T, chapters map[string]*chapterStatusT, info scanInfoT) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "scanner_output.json")
+	if err := writeJSON(scannerOutputT{"Chapters": chapters}, leaderAnalysisT{}, info, filename); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestNotifyGitHub(t *testing.T) {
+	testConfig(t)
+	gh, url := newFakeGitHubIssues(t)
+
+	oldWiki := Finding{RuleID: "old-wiki", File: "index.md", Line: 3, Severity: Policy, Message: "Old wiki link"}
+	noLeaders := Finding{RuleID: "leaders-file", Severity: Policy, Message: "No leaders.md file"}
+	lowFinding := Finding{RuleID: "example-tab", File: "tab_example.md", Severity: Low, Message: "Example tab"}
+
+	// a pull request with the label isn't the tracking issue
+	gh.add("www-chapter-new", fakeIssueT{githubIssueT: githubIssueT{Title: "Fix links", Pull_request: &json.RawMessage{'{', '}'}}, Labels: []string{"policy-scanner"}})
+	fixed := gh.add("www-chapter-fixed", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: issueBody("www-chapter-fixed", []Finding{oldWiki}, "policy-scanner")}, Labels: []string{"policy-scanner"}})
+	same := gh.add("www-chapter-same", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: issueBody("www-chapter-same", []Finding{oldWiki}, "policy-scanner")}, Labels: []string{"policy-scanner"}})
+	changed := gh.add("www-chapter-changed", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: issueBody("www-chapter-changed", []Finding{oldWiki}, "policy-scanner")}, Labels: []string{"policy-scanner"}})
+	notRun := gh.add("www-chapter-meetup", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: issueBody("www-chapter-meetup", []Finding{{RuleID: "meetup-exists", Severity: Policy, Message: "No Meetup group"}}, "policy-scanner")}, Labels: []string{"policy-scanner"}})
+	otherLabel := gh.add("www-chapter-clean", fakeIssueT{githubIssueT: githubIssueT{Title: "Something else"}, Labels: []string{"question"}})
+
+	input := writeTestOutput(t, map[string]*chapterStatusT{
+		"www-chapter-new":     {Findings: []Finding{oldWiki, lowFinding}},
+		"www-chapter-fixed":   {Findings: []Finding{lowFinding}},
+		"www-chapter-same":    {Findings: []Finding{oldWiki}},
+		"www-chapter-changed": {Findings: []Finding{oldWiki, noLeaders}},
+		"www-chapter-meetup":  {},
+		"www-chapter-clean":   {},
+	}, scanInfoT{Rules: []string{"old-wiki", "leaders-file", "example-tab"}})
+
+	if err := runNotifyGitHub([]string{"-input", input, "-githuburl", url, "-githubkey", "key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	issues := gh.repos["www-chapter-new"]
+	if len(issues) != 2 {
+		t.Fatalf("www-chapter-new has %d issues, want a new one as well as the pull request", len(issues))
+	}
+	opened := issues[1]
+	if opened.Title != issueTitle || !contains(opened.Labels, "policy-scanner") {
+		t.Errorf("opened %+v, want the tracking issue with the label", opened)
+	}
+	if opened.Body != issueBody("www-chapter-new", []Finding{oldWiki}, "policy-scanner") {
+		t.Errorf("opened issue body = %q", opened.Body)
+	}
+	if !strings.Contains(opened.Body, "[index.md line 3](https://github.com/OWASP/www-chapter-new/blob/HEAD/index.md#L3): Old wiki link") {
+		t.Errorf("opened issue body doesn't link the finding: %q", opened.Body)
+	}
+
+	if fixed.State != "closed" || len(fixed.Comments) != 1 {
+		t.Errorf("fixed issue = %+v, want it closed with a comment", fixed)
+	}
+	if same.Body != issueBody("www-chapter-same", []Finding{oldWiki}, "policy-scanner") || same.State != "open" {
+		t.Errorf("unchanged issue = %+v, want it left open as it was", same)
+	}
+	if changed.Body != issueBody("www-chapter-changed", []Finding{oldWiki, noLeaders}, "policy-scanner") || changed.State != "open" {
+		t.Errorf("changed issue body = %q, want both violations", changed.Body)
+	}
+	if notRun.State != "open" || len(notRun.Comments) != 0 {
+		t.Errorf("issue for a rule that didn't run = %+v, want it left alone", notRun)
+	}
+	if otherLabel.State != "open" || len(gh.repos["www-chapter-clean"]) != 1 {
+		t.Errorf("www-chapter-clean issues = %+v, want only the unrelated issue", gh.repos["www-chapter-clean"])
+	}
+
+	// opened, closed with a comment, and updated
+	if gh.writes != 4 {
+		t.Errorf("%d writes, want 4", gh.writes)
+	}
+}
+
+func TestNotifyGitHubDryRun(t *testing.T) {
+	testConfig(t)
+	gh, url := newFakeGitHubIssues(t)
+	gh.add("www-chapter-fixed", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: "old"}, Labels: []string{"policy-scanner"}})
+
+	input := writeTestOutput(t, map[string]*chapterStatusT{
+		"www-chapter-new":   {Findings: []Finding{{RuleID: "old-wiki", Severity: Policy, Message: "Old wiki link"}}},
+		"www-chapter-fixed": {},
+	}, scanInfoT{Rules: []string{"old-wiki"}})
+
+	if err := runNotifyGitHub([]string{"-input", input, "-githuburl", url, "-githubkey", "key", "-dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if gh.writes != 0 {
+		t.Errorf("-dry-run made %d writes", gh.writes)
+	}
+}
+
+func TestNotifyGitHubRefusesInput(t *testing.T) {
+	testConfig(t)
+	gh, url := newFakeGitHubIssues(t)
+	chapters := map[string]*chapterStatusT{"www-chapter-fixed": {}}
+	gh.add("www-chapter-fixed", fakeIssueT{githubIssueT: githubIssueT{Title: issueTitle, Body: "old"}, Labels: []string{"policy-scanner"}})
+
+	// written before the scan was recorded, with only the chapters
+	legacy := filepath.Join(t.TempDir(), "legacy.json")
+	data, err := json.Marshal(chapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(legacy, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"a baseline", writeTestOutput(t, chapters, scanInfoT{Rules: []string{"old-wiki"}, Baseline: "baseline.json"}), "was scanned with -baseline baseline.json"},
+		{"no record of the rules", legacy, "doesn't record which rules were run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runNotifyGitHub([]string{"-input", tt.input, "-githuburl", url, "-githubkey", "key"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if gh.writes != 0 || gh.repos["www-chapter-fixed"][0].State != "open" {
+		t.Errorf("refused input made %d writes", gh.writes)
+	}
+}
+
+func TestIssueRules(t *testing.T) {
+	var all []string
+	for _, c := range registry {
+		all = append(all, c.ID())
+	}
+
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"Violations\n\n<!-- policy-scanner rules: leaders-file,old-wiki -->\n", []string{"leaders-file", "old-wiki"}},
+		{"Violations\n\n<!-- policy-scanner rules:  -->\n", nil},
+		{"Violations listed before the rules were", all},
+	}
+
+	for _, tt := range tests {
+		assertStrings(t, "issueRules", issueRules(tt.body), tt.want)
+	}
+}
+
+func TestIssueBody(t *testing.T) {
+	testConfig(t)
+	violations := []Finding{
+		{RuleID: "old-wiki", File: "index.md", Line: 3, Message: "Old wiki link found in chapters/www-chapter-london/index.md on line 3"},
+		{RuleID: "site-present", File: "_site", Message: "Site directory is present at /home/scanner/chapters/www-chapter-london/_site"},
+		{RuleID: "leader-count", Message: "Malformed leader entry in chapters/www-chapter-london/leaders.md on line 4: no email address"},
+	}
+
+	body := issueBody("www-chapter-london", violations, "policy-scanner")
+
+	for _, want := range []string{
+		"- [index.md line 3](https://github.com/OWASP/www-chapter-london/blob/HEAD/index.md#L3): Old wiki link found in index.md on line 3\n",
+		"- [_site](https://github.com/OWASP/www-chapter-london/blob/HEAD/_site): Site directory is present at _site\n",
+		"- Malformed leader entry in leaders.md on line 4: no email address\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body doesn't contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "chapters/") {
+		t.Errorf("body names the scanner's checkout:\n%s", body)
+	}
+}
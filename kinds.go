@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// repoKindT is a kind of OWASP website repo. Chapters, projects, committees
+// and events are all built on the same Jekyll template, but each has its own
+// policies and its own section of scanner_output.json.
+type repoKindT struct {
+	name    string // as used by -kinds and the kinds of a rule
+	prefix  string
+	section string
+
+	// number of people leaders.md should list, max 0 is no limit
+	minLeaders int
+	maxLeaders int
+	leaders    string
+
+	// domain the leaders' email addresses must be in, empty for any
+	emailDomain string
+}
+
+var repoKinds = []repoKindT{
+	{name: "chapter", prefix: "www-chapter", section: "Chapters", minLeaders: 2, maxLeaders: 5, leaders: "leaders", emailDomain: "owasp.org"},
+	{name: "project", prefix: "www-project", section: "Projects", minLeaders: 2, leaders: "leaders"},
+	{name: "committee", prefix: "www-committee", section: "Committees", minLeaders: 3, leaders: "members"},
+	{name: "event", prefix: "www-event", section: "Events", minLeaders: 1, leaders: "organizers"},
+}
+
+// kindOf returns the kind of the named repo, or nil if it isn't a website repo
+func kindOf(name string) *repoKindT {
+	for i := range repoKinds {
+		if strings.HasPrefix(name, repoKinds[i].prefix) {
+			return &repoKinds[i]
+		}
+	}
+
+	return nil
+}
+
+func findKind(name string) *repoKindT {
+	for i := range repoKinds {
+		if repoKinds[i].name == name {
+			return &repoKinds[i]
+		}
+	}
+
+	return nil
+}
+
+// selectedKinds returns the kinds chosen with -kinds, or all of them
+func selectedKinds() ([]repoKindT, error) {
+	names := splitIDs(config.kinds)
+	if len(names) == 0 {
+		return repoKinds, nil
+	}
+
+	var kinds []repoKindT
+	for name := range names {
+		if findKind(name) == nil {
+			return nil, fmt.Errorf("unknown repo kind %q in -kinds, expected %s", name, kindNames())
+		}
+	}
+	for _, k := range repoKinds {
+		if names[k.name] {
+			kinds = append(kinds, k)
+		}
+	}
+
+	return kinds, nil
+}
+
+func containsKind(kinds []repoKindT, name string) bool {
+	for _, k := range kinds {
+		if k.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func kindNames() string {
+	var names []string
+	for _, k := range repoKinds {
+		names = append(names, k.name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// appliesTo reports whether check runs against repos of the given kind.
+// Checks without kinds run against every repo.
+func appliesTo(check Check, kind string) bool {
+	kinds := check.Kinds()
+	if len(kinds) == 0 {
+		return true
+	}
+
+	return contains(kinds, kind)
+}
+
+// the scanner_output.json section describing the scan itself
+const scanInfoSection = "Scan"
+
+// scanInfoT records how the output was made, so the commands reading it know
+// which findings it can be trusted for
+type scanInfoT struct {
+	Rules    []string // the rules that ran, see ranRules
+	Baseline string   // findings in this baseline file were left out
+}
+
+func newScanInfo() scanInfoT {
+	return scanInfoT{Rules: ranRules(activeChecks), Baseline: config.baseline}
+}
+
+// ran reports whether the rule ran. Output written before the scan was
+// recorded is nil, and is taken to have run the rules that don't need a flag.
+func (info *scanInfoT) ran(id string) bool {
+	if info != nil {
+		return contains(info.Rules, id)
+	}
+
+	for _, c := range registry {
+		if check, ok := c.(*checkT); ok && check.id == id {
+			return check.needs == nil
+		}
+	}
+
+	return true
+}
+
+// baseline is the -baseline file the scan left findings out of, "" if none or
+// the scan wasn't recorded
+func (info *scanInfoT) baseline() string {
+	if info == nil {
+		return ""
+	}
+	return info.Baseline
+}
+
+// scannerOutputT is scanner_output.json, with each kind of repo in its own
+// section, e.g. "Chapters" or "Projects"
+type scannerOutputT map[string]map[string]*chapterStatusT
+
+func newScannerOutput(kinds []repoKindT, scans []*chapterScanT) scannerOutputT {
+	output := scannerOutputT{}
+	for _, k := range kinds {
+		output[k.section] = map[string]*chapterStatusT{}
+	}
+
+	for _, c := range scans {
+		section := findKind(c.kind).section
+		if output[section] == nil {
+			output[section] = map[string]*chapterStatusT{}
+		}
+		output[section][c.name] = c.status
+	}
+
+	return output
+}
+
+// Project level metadata in index.md is missing or invalid
+//
+// The project level and type are used to sort projects on the OWASP site.
+// https://owasp.org/www-committee-project/#div-projects
+func checkProjectMetadata(c *chapterScanT, filename string, d fs.DirEntry) error {
+	if filepath.Dir(filename) != filepath.Clean(c.path) {
+		return nil
+	}
+
+	found := func(line int, msg string) {
+		c.reportFinding(Finding{
+			RuleID:   "project-metadata",
+			Severity: Medium,
+			File:     filename,
+			Line:     line,
+			Message:  msg,
+		})
+		c.status.ProjectMetadata = true
+	}
+
+	fm, err := parseFrontMatter(filename)
+	if err != nil {
+		found(1, "Unable to read project metadata: "+err.Error())
+		return nil
+	}
+	if fm == nil {
+		found(1, "No project metadata in "+filename)
+		return nil
+	}
+
+	if level, line := fm.get("level"); level == nil {
+		found(line, "Project has no level in "+filename)
+	} else if !contains([]string{"2", "3", "4"}, level.Value) {
+		found(line, fmt.Sprintf("Project level %q is not 2 (incubator), 3 (lab) or 4 (flagship) in %s on line %d", level.Value, filename, line))
+	}
+
+	if projectType, line := fm.get("type"); projectType == nil {
+		found(line, "Project has no type in "+filename)
+	} else if !contains([]string{"code", "documentation", "tool"}, projectType.Value) {
+		found(line, fmt.Sprintf("Project type %q is not code, documentation or tool in %s on line %d", projectType.Value, filename, line))
+	}
+
+	if pitch, line := fm.get("pitch"); pitch == nil || strings.TrimSpace(pitch.Value) == "" {
+		found(line, "Project has no pitch in "+filename)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// leaderT is a leader listed in leaders.md
+type leaderT struct {
+	Name  string
+	Email string
+	Role  string // any text after the name and email, e.g. "Chapter Lead"
+	Line  int
+}
+
+// leaderProblemT is an entry in leaders.md that isn't a leader with an email
+// address
+type leaderProblemT struct {
+	Line    int
+	Text    string
+	Problem string
+}
+
+var (
+	listItem     = regexp.MustCompile(`^\s*(?:[*+-]|[0-9]+\.)\s+`)
+	markdownLink = regexp.MustCompile(`\[([^\]]*)\]\(\s*<?([^)<>\s]*)>?(?:\s+"[^"]*")?\s*\)`)
+	angleAddr    = regexp.MustCompile(`<(?:mailto:)?([^<>\s]+@[^<>\s]+)>`)
+	bareAddr     = regexp.MustCompile(`[^\s<>()\[\]"',;:]+@[^\s<>()\[\]"',;:]+\.[A-Za-z]{2,}`)
+)
+
+// parseLeaders reads the leaders in leaders.md. Each list item is a leader,
+// in one of these forms:
+//
+//   - [Jane Doe](mailto:jane.doe@owasp.org) - Chapter Lead
+//   - [Jane Doe](jane.doe@owasp.org)
+//   - Jane Doe <jane.doe@owasp.org>, Chapter Lead
+//   - Jane Doe jane.doe@owasp.org
+//
+// Headings, the front matter and other text, such as a chapter email address,
+// are skipped. List items without a valid email address, and leaders listed
+// twice, are returned as problems.
+func parseLeaders(r io.Reader) ([]leaderT, []leaderProblemT, error) {
+	var leaders []leaderT
+	var problems []leaderProblemT
+	seen := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+
+	line := 0
+	frontMatter := false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "---" && (line == 1 || frontMatter) {
+			frontMatter = !frontMatter
+			continue
+		}
+		if frontMatter || text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "<!--") {
+			continue
+		}
+
+		if !listItem.MatchString(text) {
+			continue
+		}
+
+		leader, problem := parseLeader(listItem.ReplaceAllString(text, ""))
+		if problem == "" {
+			key := strings.ToLower(leader.Email)
+			if first, ok := seen[key]; ok {
+				problem = fmt.Sprintf("%s is already listed on line %d", leader.Email, first)
+			} else {
+				seen[key] = line
+			}
+		}
+
+		if problem != "" {
+			problems = append(problems, leaderProblemT{Line: line, Text: scanner.Text(), Problem: problem})
+			continue
+		}
+
+		leader.Line = line
+		leaders = append(leaders, leader)
+	}
+
+	return leaders, problems, scanner.Err()
+}
+
+// Leaders without an address in the domain the repo kind requires. leaders.md
+// is parsed again, so the check doesn't depend on leader-count being enabled.
+func checkLeaderEmailDomain(c *chapterScanT, filename string, d fs.DirEntry) error {
+	domain := findKind(c.kind).emailDomain
+	if domain == "" || !isLeadersFile(c, filename) {
+		return nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// malformed entries are reported by leader-count
+	leaders, _, err := parseLeaders(f)
+	if err != nil {
+		return err
+	}
+
+	for _, leader := range leaders {
+		if inDomain(leader.Email, domain) {
+			continue
+		}
+
+		c.reportFinding(Finding{
+			RuleID:   "leader-email-domain",
+			Severity: Policy,
+			File:     filename,
+			Line:     leader.Line,
+			Message:  fmt.Sprintf("%s is listed on line %d of leaders.md, which isn't an %s address", leader.Email, leader.Line, domain),
+			Snippet:  leader.Email,
+		})
+	}
+
+	return nil
+}
+
+func inDomain(email string, domain string) bool {
+	return strings.HasSuffix(strings.ToLower(email), "@"+domain)
+}
+
+// isLeadersFile reports whether filename is the repo's leaders.md. Only
+// www-chapter-<chaptername>/leaders.md lists the leaders, the leaders tab is
+// not the official source of leadership information.
+func isLeadersFile(c *chapterScanT, filename string) bool {
+	return !strings.HasSuffix(filename, "tab_leaders.md") && filepath.Dir(filename) == filepath.Clean(c.path)
+}
+
+// parseLeader finds the name, email and role in an entry, or returns what is
+// wrong with it
+func parseLeader(text string) (leaderT, string) {
+	var leader leaderT
+	rest := text
+
+	if m := markdownLink.FindStringSubmatchIndex(text); m != nil {
+		label := text[m[2]:m[3]]
+		target := text[m[4]:m[5]]
+
+		switch {
+		case strings.HasPrefix(strings.ToLower(target), "mailto:"):
+			leader.Email = target[len("mailto:"):]
+			if i := strings.Index(leader.Email, "?"); i >= 0 {
+				leader.Email = leader.Email[:i]
+			}
+			if unescaped, err := url.PathUnescape(leader.Email); err == nil {
+				leader.Email = unescaped
+			}
+		case strings.Contains(target, "@") && !strings.Contains(target, "://"):
+			leader.Email = target
+		case bareAddr.MatchString(label):
+			leader.Email = bareAddr.FindString(label)
+			label = ""
+		}
+
+		if leader.Email != "" {
+			if !strings.Contains(label, "@") {
+				leader.Name = label
+			}
+			rest = text[:m[0]] + " " + text[m[1]:]
+		}
+	}
+
+	if leader.Email == "" {
+		if m := angleAddr.FindStringSubmatchIndex(text); m != nil {
+			leader.Email = text[m[2]:m[3]]
+			leader.Name = strings.Trim(strings.TrimSpace(text[:m[0]]), `"`)
+			rest = text[m[1]:]
+		} else if m := bareAddr.FindStringIndex(text); m != nil {
+			leader.Email = strings.TrimPrefix(text[m[0]:m[1]], "mailto:")
+			leader.Name = strings.TrimSpace(text[:m[0]])
+			rest = text[m[1]:]
+		}
+	}
+
+	if leader.Email == "" {
+		return leader, "no email address"
+	}
+
+	addr, err := mail.ParseAddress(leader.Email)
+	if err != nil || addr.Address != leader.Email {
+		return leader, fmt.Sprintf("%q is not a valid email address", leader.Email)
+	}
+
+	leader.Name = strings.Trim(leader.Name, " -–—,:|*_")
+	leader.Role = strings.Trim(strings.TrimSpace(rest), " -–—,:|()*_")
+
+	return leader, ""
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLeader(t *testing.T) {
+	tests := []struct {
+		text        string
+		want        leaderT
+		wantProblem string
+	}{
+		{"[Jane Doe](mailto:jane.doe@owasp.org) - Chapter Lead", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org", Role: "Chapter Lead"}, ""},
+		{"[Jane Doe](jane.doe@owasp.org)", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org"}, ""},
+		{"[Jane Doe](mailto:jane.doe@owasp.org?subject=Hello)", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org"}, ""},
+		{"[Jane Doe](mailto:jane%2Bchapter@owasp.org)", leaderT{Name: "Jane Doe", Email: "jane+chapter@owasp.org"}, ""},
+		{"[jane.doe@owasp.org](mailto:jane.doe@owasp.org)", leaderT{Email: "jane.doe@owasp.org"}, ""},
+		{"Jane Doe <jane.doe@owasp.org>, Chapter Lead", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org", Role: "Chapter Lead"}, ""},
+		{`"Jane Doe" <mailto:jane.doe@owasp.org>`, leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org"}, ""},
+		{"Jane Doe jane.doe@owasp.org", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org"}, ""},
+		{"**Jane Doe** - jane.doe@owasp.org - Co-Lead", leaderT{Name: "Jane Doe", Email: "jane.doe@owasp.org", Role: "Co-Lead"}, ""},
+		{"[Jane Doe](https://linkedin.com/in/jane)", leaderT{}, "no email address"},
+		{"Jane Doe", leaderT{}, "no email address"},
+		{"[Jane Doe](mailto:jane.doe)", leaderT{Name: "", Email: "jane.doe"}, `"jane.doe" is not a valid email address`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, problem := parseLeader(tt.text)
+			if problem != tt.wantProblem {
+				t.Fatalf("problem = %q, want %q", problem, tt.wantProblem)
+			}
+			if problem == "" && got != tt.want {
+				t.Errorf("leader = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLeaders(t *testing.T) {
+	md := strings.Join([]string{
+		"---",
+		"title: Leaders",
+		"- not@a.leader.com",
+		"---",
+		"### Leaders",
+		"<!-- - [Old Leader](mailto:old@owasp.org) -->",
+		"",
+		"* [Jane Doe](mailto:jane.doe@owasp.org) - Chapter Lead",
+		"  1. Bob <bob@owasp.org>",
+		"Contact us at london@owasp.org",
+		"+ [Someone](https://example.com)",
+		"- [Jane again](mailto:Jane.Doe@owasp.org)",
+		"- Tom tom@",
+	}, "\n")
+
+	leaders, problems, err := parseLeaders(strings.NewReader(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantLeaders := []leaderT{
+		{Name: "Jane Doe", Email: "jane.doe@owasp.org", Role: "Chapter Lead", Line: 8},
+		{Name: "Bob", Email: "bob@owasp.org", Line: 9},
+	}
+	if !reflect.DeepEqual(leaders, wantLeaders) {
+		t.Errorf("leaders = %+v, want %+v", leaders, wantLeaders)
+	}
+
+	wantProblems := []leaderProblemT{
+		{Line: 11, Text: "+ [Someone](https://example.com)", Problem: "no email address"},
+		{Line: 12, Text: "- [Jane again](mailto:Jane.Doe@owasp.org)", Problem: "Jane.Doe@owasp.org is already listed on line 8"},
+		{Line: 13, Text: "- Tom tom@", Problem: "no email address"},
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("problems = %+v, want %+v", problems, wantProblems)
+	}
+}
+
+func TestCheckLeaderEmailDomain(t *testing.T) {
+	md := strings.Join([]string{
+		"### Leaders",
+		"* [Jane Doe](mailto:Jane.Doe@OWASP.org)",
+		"* [Bob](mailto:bob@example.com)",
+		"* Alice alice@owasp.org.example.com",
+		"* [Someone](https://example.com)",
+	}, "\n")
+
+	tests := []struct {
+		repo         string
+		file         string
+		wantFindings []string
+	}{
+		{"www-chapter-london", "leaders.md", []string{
+			"bob@example.com is listed on line 3 of leaders.md, which isn't an owasp.org address",
+			"alice@owasp.org.example.com is listed on line 4 of leaders.md, which isn't an owasp.org address",
+		}},
+		{"www-chapter-london", "tab_leaders.md", nil},
+		{"www-chapter-london", filepath.Join("old", "leaders.md"), nil},
+		// only chapter leaders need an owasp.org address
+		{"www-project-zap", "leaders.md", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo+"/"+tt.file, func(t *testing.T) {
+			testConfig(t)
+			dir := t.TempDir()
+			filename := filepath.Join(dir, tt.file)
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filename, []byte(md), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// without leader-count having run
+			c := newTestScan(tt.repo, dir)
+			if err := checkLeaderEmailDomain(c, filename, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			assertStrings(t, "findings", findingMessages(c.status.Findings), tt.wantFindings)
+			for _, f := range c.status.Findings {
+				if f.RuleID != "leader-email-domain" || f.File != "leaders.md" || f.Severity != Policy {
+					t.Errorf("finding = %+v, want a leader-email-domain policy finding in leaders.md", f)
+				}
+			}
+		})
+	}
+}
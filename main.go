@@ -4,22 +4,18 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/mail"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var currChapter string
-
 type StatusLevelT int
 
 const (
@@ -50,14 +46,22 @@ const (
 type chapterStatusT struct {
 	AutoMigration          bool
 	ConfigYml              bool
+	CopperLeadersNotListed []string
 	DefaultText            bool
 	ExampleTab             bool
+	Fixed                  []string
 	GitHub                 serviceStatusT
 	GoogleForms            privacyStatusT
+	LeaderEmails           []string
+	LeaderList             []leaderT
 	Leaders                int
+	LeadersNotInCopper     []string
 	Meetup                 serviceStatusT
+	MeetupEvents           []meetupEventT
+	MeetupMembers          int
 	MeetupMetaData         serviceStatusT
 	MeetupName             string
+	MeetupOrganizers       []string
 	MeetupPastMeetings     int
 	MeetupUpcomingMeetings int
 	OldDonate              bool
@@ -67,67 +71,73 @@ type chapterStatusT struct {
 	OldProjects            bool
 	OldSpeaker             bool
 	OldWiki                bool
+	PlatformPastEvents     int
+	PlatformUpcomingEvents int
+	Platforms              []string
+	ProjectMetadata        bool
 	SitePresent            bool
+	TabTags                bool
+	Findings               []Finding
 }
 
-var chapterStatus = map[string]*chapterStatusT{}
+func writeJSON(output scannerOutputT, analysis leaderAnalysisT, info scanInfoT, filename string) error {
 
-func writeJSON() {
+	sections := map[string]interface{}{leaderAnalysisSection: analysis, scanInfoSection: info}
+	for section, repos := range output {
+		sections[section] = repos
+	}
 
-	file, err := json.MarshalIndent(chapterStatus, "", " ")
+	file, err := json.MarshalIndent(sections, "", " ")
 	if err != nil {
 		println("Error marshalling chapterStatus")
-		return
+		return err
 	}
-	err = ioutil.WriteFile("scanner_output.json", file, 0644)
+	err = ioutil.WriteFile(filename, file, 0644)
 	if err != nil {
 		println("Error writing JSON to disk")
-		return
+		return err
 	}
+
+	return nil
 }
 
 func printStatus(sl StatusLevelT, s string) {
+	writeStatus(os.Stdout, sl, s)
+}
+
+func writeStatus(w io.Writer, sl StatusLevelT, s string) {
 	if config.policy && sl < Policy {
 		return
 	}
 
 	switch sl {
 	case Info:
-		fmt.Print("Info: ")
+		fmt.Fprint(w, "Info: ")
 		break
 
 	case Low:
-		fmt.Print("Low: ")
+		fmt.Fprint(w, "Low: ")
 		break
 
 	case Medium:
-		fmt.Print("Medium: ")
+		fmt.Fprint(w, "Medium: ")
 		break
 
 	case High:
-		fmt.Print("High: ")
+		fmt.Fprint(w, "High: ")
 		break
 
 	case Policy:
-		fmt.Print("POLICY: ")
+		fmt.Fprint(w, "POLICY: ")
 		break
 
 	}
 
-	fmt.Println(s)
-}
-
-// Out of date dependencies in _config.yml
-func checkConfigYml(s string, d fs.DirEntry) {
-
+	fmt.Fprintln(w, s)
 }
 
 // Default text in index.md
-func checkDefaultText(filename string, d fs.DirEntry) error {
-	if !strings.Contains(filename, ".md") {
-		return nil
-	}
-
+func checkDefaultText(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -141,36 +151,43 @@ func checkDefaultText(filename string, d fs.DirEntry) error {
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "Standard Chapter Page Template") {
-			printStatus(Policy, fmt.Sprintf("Default text present in %s on line %d", filename, line))
-			chapterStatus[currChapter].DefaultText = true
+			c.reportFinding(Finding{
+				RuleID:   "default-text",
+				Severity: Policy,
+				File:     filename,
+				Line:     line,
+				Column:   column(scanner.Text(), "Standard Chapter Page Template"),
+				Message:  fmt.Sprintf("Default text present in %s on line %d", filename, line),
+				Snippet:  scanner.Text(),
+			})
+			c.status.DefaultText = true
 			return nil
 		}
 		line++
 	}
 
 	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkDefaultText error: "+err.Error())
+		c.printStatus(Info, "checkDefaultText error: "+err.Error())
 	}
 
 	return nil
 }
 
 // Default tab tab_example.md is present
-func checkDefaultExampleTab(filename string, d fs.DirEntry) error {
-	if strings.Contains(filename, "tab_example.md") {
-		printStatus(Low, "Example tab found at: "+filename)
-		chapterStatus[currChapter].ExampleTab = true
-	}
+func checkDefaultExampleTab(c *chapterScanT, filename string, d fs.DirEntry) error {
+	c.reportFinding(Finding{
+		RuleID:   "example-tab",
+		Severity: Low,
+		File:     filename,
+		Message:  "Example tab found at: " + filename,
+	})
+	c.status.ExampleTab = true
 
 	return nil
 }
 
 // Automigration metadata is present and set to 1
-func checkDefaultMigrationHeader(filename string, d fs.DirEntry) error {
-	if !strings.Contains(filename, "index.md") {
-		return nil
-	}
-
+func checkDefaultMigrationHeader(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -184,35 +201,31 @@ func checkDefaultMigrationHeader(filename string, d fs.DirEntry) error {
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "auto-migrated: 1") {
-			printStatus(Policy, fmt.Sprintf("Auto-Migration Headers active in %s on line %d", filename, line))
-			chapterStatus[currChapter].AutoMigration = true
+			c.reportFinding(Finding{
+				RuleID:   "migration-header",
+				Severity: Policy,
+				File:     filename,
+				Line:     line,
+				Column:   column(scanner.Text(), "auto-migrated: 1"),
+				Message:  fmt.Sprintf("Auto-Migration Headers active in %s on line %d", filename, line),
+				Snippet:  scanner.Text(),
+			})
+			c.status.AutoMigration = true
 			return nil
 		}
 		line++
 	}
 
 	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkDefaultMigrationHeader error: "+err.Error())
+		c.printStatus(Info, "checkDefaultMigrationHeader error: "+err.Error())
 	}
 
 	return nil
 }
 
 // Number of leaders < 2 or > 5
-func checkLeaderCount(filename string, d fs.DirEntry) error {
-	if !strings.HasSuffix(filename, "leaders.md") {
-		return nil
-	}
-
-	// the leaders tab is not the official source of leadership information
-	if strings.HasSuffix(filename, "tab_leaders.md") {
-		return nil
-	}
-
-	// Check that only the top leaders.md file is parsed
-	// Only www-chapter-<chaptername>/leaders.md counts as a leader
-	reg := regexp.MustCompile(`chapters\/www-chapter-.*\/(.*)(/)leaders.md`)
-	if reg.MatchString(filename) {
+func checkLeaderCount(c *chapterScanT, filename string, d fs.DirEntry) error {
+	if !isLeadersFile(c, filename) {
 		return nil
 	}
 
@@ -222,99 +235,44 @@ func checkLeaderCount(filename string, d fs.DirEntry) error {
 	}
 	defer f.Close()
 
-	// Splits on newlines by default.
-	scanner := bufio.NewScanner(f)
-
-	leaders := 0
-
-	// https://golang.org/pkg/bufio/#Scanner.Scan
-	for scanner.Scan() {
-
-		email := scanner.Text()
-		email = strings.TrimLeft(email, "* ")
-		email = strings.TrimLeft(email, "- ")
-		email = strings.TrimSpace(email)
-
-		// skip blank lines
-		if email == "" {
-			continue
-		}
-
-		// Skip headers
-		if strings.Contains(email, "#") {
-			continue
-		}
-
-		// Skip lines without an @ symbol
-		if !strings.Contains(email, "@") {
-			continue
-		}
-
-		// Check to see if it's a Markdown mailto: and trim accordingly
-		if strings.Contains(email, "[") && strings.Contains(email, "mailto") {
-			// trim to just the email address bit
-			reg := regexp.MustCompile(`^.*mailto:`)
-			email = reg.ReplaceAllString(email, "${1}")
-			reg = regexp.MustCompile(`\).*$`)
-			email = reg.ReplaceAllLiteralString(email, "")
-			email = strings.Replace(email, "/", "", -1)
-		}
-
-		// Check to see if it's a Mardown without a mailto
-		if strings.Contains(email, "[") && !strings.Contains(email, "mailto") {
-			// trim to just the email address bit
-			reg := regexp.MustCompile(`^.*\(`)
-			email = reg.ReplaceAllString(email, "${1}")
-			reg = regexp.MustCompile(`\).*$`)
-			email = reg.ReplaceAllLiteralString(email, "")
-			email = strings.Replace(email, "/", "", -1)
-		}
-
-		// Some emails are like this "* <demo text>" but aren't real
-
-		if strings.HasPrefix(email, "* ") || strings.HasPrefix(email, "- ") {
-			email = strings.Replace(email, "* ", "", 1)
-			email = strings.Replace(email, "- ", "", 1)
-		}
-
-		email = strings.TrimSpace(email)
-
-		_, err := mail.ParseAddress(email)
-		if err != nil {
-			if err.Error() == "mail: no angle-addr" {
-				printStatus(Low, "checkLeaderCount leader has no email: "+email)
-			} else {
-				printStatus(Info, "checkLeaderCount error: "+err.Error())
-			}
-		} else {
-			leaders++
-		}
+	leaders, problems, err := parseLeaders(f)
+	if err != nil {
+		return err
 	}
 
-	if leaders < 2 || leaders > 5 {
-		printStatus(Policy, fmt.Sprintf("%s has %d leaders", currChapter, leaders))
-		chapterStatus[currChapter].Leaders = leaders
+	for _, p := range problems {
+		c.reportFinding(Finding{
+			RuleID:   "leader-count",
+			Severity: Low,
+			File:     filename,
+			Line:     p.Line,
+			Message:  fmt.Sprintf("Malformed leader entry in %s on line %d: %s", filename, p.Line, p.Problem),
+			Snippet:  p.Text,
+		})
 	}
 
-	chapterStatus[currChapter].Leaders = leaders
+	c.status.LeaderList = leaders
+	for _, leader := range leaders {
+		c.status.LeaderEmails = append(c.status.LeaderEmails, leader.Email)
+	}
 
-	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkLeaderCount error: "+err.Error())
+	kind := findKind(c.kind)
+	if len(leaders) < kind.minLeaders || (kind.maxLeaders > 0 && len(leaders) > kind.maxLeaders) {
+		c.reportFinding(Finding{
+			RuleID:   "leader-count",
+			Severity: Policy,
+			File:     filename,
+			Message:  fmt.Sprintf("%s has %d %s", c.name, len(leaders), kind.leaders),
+		})
 	}
 
-	return nil
-}
+	c.status.Leaders = len(leaders)
 
-// Leaders in leaders.md doesn’t match Copper
-func checkLeadersInCopper(s string, d fs.DirEntry) {
+	return nil
 }
 
 // Out of date .gitignore
-func checkOldGitIgnore(filename string, d fs.DirEntry) error {
-	if !strings.HasSuffix(filename, ".gitignore") {
-		return nil
-	}
-
+func checkOldGitIgnore(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -327,7 +285,6 @@ func checkOldGitIgnore(filename string, d fs.DirEntry) error {
 	hasSite := false
 	hasGemfile := false
 
-	line := 1
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "_site") {
@@ -337,69 +294,88 @@ func checkOldGitIgnore(filename string, d fs.DirEntry) error {
 		if strings.Contains(scanner.Text(), "Gemfile.lock") {
 			hasGemfile = true
 		}
-
-		line++
 	}
 
-	if hasSite {
-		printStatus(Info, ".gitignore does not have _site in file "+filename)
-		chapterStatus[currChapter].OldGitIgnore = true
+	if err := scanner.Err(); err != nil {
+		return err
 	}
 
-	if hasGemfile {
-		printStatus(Info, ".gitignore does not have Gemfile.lock in file "+filename)
-		chapterStatus[currChapter].OldGitIgnore = true
+	if !hasSite {
+		c.reportFinding(Finding{
+			RuleID:   "old-gitignore",
+			Severity: Info,
+			File:     filename,
+			Message:  ".gitignore does not have _site in file " + filename,
+		})
+		c.status.OldGitIgnore = true
 	}
 
-	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkMeetupMissingMetaData error: "+err.Error())
+	if !hasGemfile {
+		c.reportFinding(Finding{
+			RuleID:   "old-gitignore",
+			Severity: Info,
+			File:     filename,
+			Message:  ".gitignore does not have Gemfile.lock in file " + filename,
+		})
+		c.status.OldGitIgnore = true
 	}
 
 	return nil
 }
 
 // _site/ being present
-func checkIfSite(s string, d fs.DirEntry) {
-	if d.IsDir() {
-		if d.Name() == "_site" {
-			printStatus(Low, "Site directory is present at "+s)
-			chapterStatus[currChapter].SitePresent = true
-		}
-	}
+func checkIfSite(c *chapterScanT, s string, d fs.DirEntry) error {
+	c.reportFinding(Finding{
+		RuleID:   "site-present",
+		Severity: Low,
+		File:     s,
+		Message:  "Site directory is present at " + s,
+	})
+	c.status.SitePresent = true
+
+	return nil
 }
 
 type PagesRespT struct {
 	Has_pages bool `json:"has_pages"`
 }
 
-func checkPagesStatus(chapterName string) error {
+func checkPagesStatus(c *chapterScanT, chapterName string) error {
 	if !config.pages {
 		return nil
 	}
 
 	// check the group is exists and active
-	reqUrl := fmt.Sprintf("https://api.github.com/repos/OWASP/%s", chapterName)
+	reqUrl := fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(config.githubURL, "/"), config.org, chapterName)
 	req, err := http.NewRequest("GET", reqUrl, nil)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	req.Header.Set("Authorization", "token "+config.githubkey)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		printStatus(Policy, "GitHub Pages does not exist for "+chapterName)
-		chapterStatus[currChapter].GitHub = nonexistant
+		c.reportFinding(Finding{
+			RuleID:   "pages-status",
+			Severity: Policy,
+			Message:  "GitHub Pages does not exist for " + chapterName,
+		})
+		c.status.GitHub = nonexistant
 		return nil
 	}
 
 	if resp.StatusCode == 410 {
-		printStatus(Policy, "GitHub Pages exists, but is disabled for "+chapterName)
-		chapterStatus[currChapter].GitHub = inactive
+		c.reportFinding(Finding{
+			RuleID:   "pages-status",
+			Severity: Policy,
+			Message:  "GitHub Pages exists, but is disabled for " + chapterName,
+		})
+		c.status.GitHub = inactive
 		return nil
 	}
 
@@ -409,49 +385,42 @@ func checkPagesStatus(chapterName string) error {
 	// Sleep if necessary to slow things down
 	if requestsLeft < 1 {
 		resetTime := requestsTimeOut - time.Now().Unix()
-		printStatus(Info, fmt.Sprintf("GitHub API limit reached, sleeping for %d seconds", resetTime))
+		c.printStatus(Info, fmt.Sprintf("GitHub API limit reached, sleeping for %d seconds", resetTime))
 		time.Sleep(time.Duration(resetTime) * time.Second)
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	var m PagesRespT
 	err = json.Unmarshal([]byte(body), &m)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	if m.Has_pages {
-		printStatus(Info, "GitHub Pages published for "+chapterName)
-		chapterStatus[currChapter].GitHub = active
+		c.printStatus(Info, "GitHub Pages published for "+chapterName)
+		c.status.GitHub = active
 	} else {
-		printStatus(Policy, "GitHub Pages are disabled for "+chapterName)
-		chapterStatus[currChapter].GitHub = inactive
+		c.reportFinding(Finding{
+			RuleID:   "pages-status",
+			Severity: Policy,
+			Message:  "GitHub Pages are disabled for " + chapterName,
+		})
+		c.status.GitHub = inactive
 	}
 
 	return nil
 }
 
-type meetupGroupRespT struct {
-	Status               string `json:"status"`
-	Upcoming_event_count int    `json:"upcoming_event_count"`
-	Past_event_count     int    `json:"past_event_count"`
-	Members              int    `json:"members"`
-}
-
 // Meetup header present but no active Meetup for that chapter
-func checkMeetupExists(filename string, d fs.DirEntry) error {
+func checkMeetupExists(c *chapterScanT, filename string, d fs.DirEntry) error {
 	if !config.meetup {
 		return nil
 	}
 
-	if !strings.HasSuffix(filename, "index.md") {
-		return nil
-	}
-
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -471,73 +440,78 @@ func checkMeetupExists(filename string, d fs.DirEntry) error {
 			meetupGroup := strings.Split(lineStr, ": ")
 
 			if len(meetupGroup) == 1 {
-				printStatus(Policy, "Meetup-group header is present but blank")
-				chapterStatus[currChapter].Meetup = nonexistant
+				c.reportFinding(Finding{
+					RuleID:   "meetup-exists",
+					Severity: Policy,
+					File:     filename,
+					Line:     line,
+					Message:  "Meetup-group header is present but blank",
+					Snippet:  lineStr,
+				})
+				c.status.Meetup = nonexistant
 				return nil
 			}
 
 			if strings.Trim(meetupGroup[1], " ") == "" {
-				printStatus(Policy, "Meetup-group header is present but blank with whitespace")
-				chapterStatus[currChapter].Meetup = nonexistant
+				c.reportFinding(Finding{
+					RuleID:   "meetup-exists",
+					Severity: Policy,
+					File:     filename,
+					Line:     line,
+					Message:  "Meetup-group header is present but blank with whitespace",
+					Snippet:  lineStr,
+				})
+				c.status.Meetup = nonexistant
 				return nil
 			}
 
-			// check the group is exists and active
-			resp, err := http.Get("https://api.meetup.com/" + meetupGroup[1] + "?fields=past_event_count,upcoming_event_count")
-			if err != nil {
-				log.Fatalln(err)
+			if config.meetupToken == "" {
+				return fmt.Errorf("-meetup needs a Meetup OAuth access token, see -meetuptoken")
 			}
 
-			if resp.StatusCode == 404 {
-				printStatus(Policy, "Meetup Group does not exist for "+meetupGroup[1])
-				chapterStatus[currChapter].Meetup = nonexistant
-				return nil
-			}
-
-			if resp.StatusCode == 410 {
-				printStatus(Policy, "Meetup exists, but is disabled for "+meetupGroup[1])
-				chapterStatus[currChapter].Meetup = inactive
-				return nil
-			}
-
-			requestsLeft, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
-			requestsTimeOut, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Reset"))
-
-			// We could go to 0, but let's not
-			if requestsLeft == 1 {
-				time.Sleep(time.Duration(requestsTimeOut) * time.Second)
-			}
-
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				log.Fatalln(err)
-			}
-
-			var m meetupGroupRespT
-			err = json.Unmarshal([]byte(body), &m)
+			// check the group is exists and active
+			m, err := newMeetupClient().group(meetupGroup[1])
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 
-			if m.Status == "active" && m.Past_event_count < 3 {
-				printStatus(Policy, fmt.Sprintf("Low past meetings. Meetup %s exists, is active, %d members, %d upcoming events, %d past events", meetupGroup[1], m.Members, m.Upcoming_event_count, m.Past_event_count))
-				chapterStatus[currChapter].Meetup = active
-				chapterStatus[currChapter].MeetupName = meetupGroup[1]
-				chapterStatus[currChapter].MeetupPastMeetings = m.Past_event_count
-				chapterStatus[currChapter].MeetupUpcomingMeetings = m.Upcoming_event_count
+			if m == nil {
+				c.reportFinding(Finding{
+					RuleID:   "meetup-exists",
+					Severity: Policy,
+					File:     filename,
+					Line:     line,
+					Message:  "Meetup Group does not exist for " + meetupGroup[1],
+					Snippet:  lineStr,
+				})
+				c.status.Meetup = nonexistant
 				return nil
 			}
 
-			if m.Status == "active" {
-				printStatus(Info, fmt.Sprintf("Meetup %s exists, is active, %d members, %d upcoming events, %d past events", meetupGroup[1], m.Members, m.Upcoming_event_count, m.Past_event_count))
-				chapterStatus[currChapter].Meetup = active
-				chapterStatus[currChapter].MeetupName = meetupGroup[1]
-				chapterStatus[currChapter].MeetupPastMeetings = m.Past_event_count
-				chapterStatus[currChapter].MeetupUpcomingMeetings = m.Upcoming_event_count
+			if !m.active() {
+				c.reportFinding(Finding{
+					RuleID:   "meetup-exists",
+					Severity: Policy,
+					File:     filename,
+					Line:     line,
+					Message:  "Meetup exists, but is disabled for " + meetupGroup[1],
+					Snippet:  lineStr,
+				})
+				c.status.Meetup = inactive
 				return nil
 			}
 
-			printStatus(Info, "DEBUG Meetup Unknown Status: "+m.Status)
+			c.status.Meetup = active
+			c.status.MeetupName = meetupGroup[1]
+			c.status.MeetupMembers = m.Memberships.TotalCount
+			c.status.MeetupPastMeetings = m.PastEvents.TotalCount
+			c.status.MeetupUpcomingMeetings = m.UpcomingEvents.TotalCount
+			c.status.MeetupEvents = m.events()
+			c.status.MeetupOrganizers = m.organizers()
+
+			// too few events is decided by event-activity, with the other platforms
+			c.printStatus(Info, fmt.Sprintf("Meetup %s exists, is active, %d members, %d upcoming events, %d past events", meetupGroup[1], m.Memberships.TotalCount, m.UpcomingEvents.TotalCount, m.PastEvents.TotalCount))
+			return nil
 		}
 		line++
 	}
@@ -546,11 +520,7 @@ func checkMeetupExists(filename string, d fs.DirEntry) error {
 }
 
 // Meetup header present and Link to Meetup in info.md but no metadata JavaScript for automated (warning)
-func checkMeetupMissingMetaData(filename string, d fs.DirEntry) error {
-	if !strings.HasSuffix(filename, ".md") {
-		return nil
-	}
-
+func checkMeetupMissingMetaData(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -562,55 +532,62 @@ func checkMeetupMissingMetaData(filename string, d fs.DirEntry) error {
 
 	hasHeader := false
 	hasJavaScript := false
+	headerLine := 0
+	javaScriptLine := 0
 
 	line := 1
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "meetup-group:") {
 			hasHeader = true
+			headerLine = line
 		}
 
 		if strings.Contains(scanner.Text(), "include chapter_events.html group=page.meetup-group") {
 			hasJavaScript = true
+			javaScriptLine = line
 		}
 
 		line++
 	}
 
-	chapterStatus[currChapter].MeetupMetaData = nonexistant
+	c.status.MeetupMetaData = nonexistant
 	if hasHeader && !hasJavaScript {
-		printStatus(Medium, "Has Meetup metadata, but JavaScript is not present in "+filename)
-		chapterStatus[currChapter].MeetupMetaData = inactive
+		c.reportFinding(Finding{
+			RuleID:   "meetup-metadata",
+			Severity: Medium,
+			File:     filename,
+			Line:     headerLine,
+			Message:  "Has Meetup metadata, but JavaScript is not present in " + filename,
+		})
+		c.status.MeetupMetaData = inactive
 	}
 
 	if !hasHeader && hasJavaScript {
-		printStatus(Medium, "No Meetup metadata, but JavaScript is present in "+filename)
-		chapterStatus[currChapter].MeetupMetaData = inactive
+		c.reportFinding(Finding{
+			RuleID:   "meetup-metadata",
+			Severity: Medium,
+			File:     filename,
+			Line:     javaScriptLine,
+			Message:  "No Meetup metadata, but JavaScript is present in " + filename,
+		})
+		c.status.MeetupMetaData = inactive
 	}
 
 	if hasHeader && hasJavaScript {
-		printStatus(Info, "Meetup metadata and JavaScript present")
-		chapterStatus[currChapter].MeetupMetaData = active
+		c.printStatus(Info, "Meetup metadata and JavaScript present")
+		c.status.MeetupMetaData = active
 	}
 
 	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkMeetupMissingMetaData error: "+err.Error())
+		c.printStatus(Info, "checkMeetupMissingMetaData error: "+err.Error())
 	}
 
 	return nil
 }
 
 // Old Wiki links are present (a warning not a breakage)
-func checkForOldWiki(filename string, d fs.DirEntry) error {
-	if !strings.Contains(filename, ".md") {
-		return nil
-	}
-
-	// If it's in the migrated content, don't care as it's not shown
-	if strings.Contains(filename, "migrated_content.md") {
-		return nil
-	}
-
+func checkForOldWiki(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -624,10 +601,16 @@ func checkForOldWiki(filename string, d fs.DirEntry) error {
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "www.owasp.org/index.php") {
-			if !config.policy {
-				printStatus(Low, fmt.Sprintf("Old wiki link found in %s on line %d", filename, line))
-				chapterStatus[currChapter].OldWiki = true
-			}
+			c.reportFinding(Finding{
+				RuleID:   "old-wiki",
+				Severity: Low,
+				File:     filename,
+				Line:     line,
+				Column:   column(scanner.Text(), "www.owasp.org/index.php"),
+				Message:  fmt.Sprintf("Old wiki link found in %s on line %d", filename, line),
+				Snippet:  scanner.Text(),
+			})
+			c.status.OldWiki = true
 			return nil
 		}
 
@@ -635,18 +618,13 @@ func checkForOldWiki(filename string, d fs.DirEntry) error {
 	}
 
 	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkForOldPolicy error: "+err.Error())
+		c.printStatus(Info, "checkForOldPolicy error: "+err.Error())
 	}
 
 	return nil
 }
 
-func checkForDonate(filename string, d fs.DirEntry) error {
-
-	if !strings.Contains(filename, ".md") {
-		return nil
-	}
-
+func checkForDonate(c *chapterScanT, filename string, d fs.DirEntry) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -660,227 +638,184 @@ func checkForDonate(filename string, d fs.DirEntry) error {
 	// https://golang.org/pkg/bufio/#Scanner.Scan
 	for scanner.Scan() {
 		if strings.Contains(scanner.Text(), "PayPal") || strings.Contains(scanner.Text(), "Paypal") {
-			printStatus(High, fmt.Sprintf("Old donate mechanism in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldDonate = true
+			c.reportFinding(Finding{
+				RuleID:   "old-donate",
+				Severity: High,
+				File:     filename,
+				Line:     line,
+				Column:   column(strings.ToLower(scanner.Text()), "paypal"),
+				Message:  fmt.Sprintf("Old donate mechanism in %s on line %d", filename, line),
+				Snippet:  scanner.Text(),
+			})
+			c.status.OldDonate = true
 		}
 
 		line++
 	}
 
 	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkForDonate error: "+err.Error())
+		c.printStatus(Info, "checkForDonate error: "+err.Error())
 	}
 
 	return nil
 }
 
-// Old policy links are present (a warning not a breakage)
-func checkForOldPolicy(filename string, d fs.DirEntry) error {
-
-	if !strings.Contains(filename, ".md") {
-		return nil
-	}
-
-	if strings.Contains(filename, "migrated_content.md") {
-		return nil
-	}
-
-	f, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	// Splits on newlines by default.
-	scanner := bufio.NewScanner(f)
-
-	line := 1
-	// https://golang.org/pkg/bufio/#Scanner.Scan
-	for scanner.Scan() {
-		if strings.Contains(scanner.Text(), "Speaker_Agreement") {
-			printStatus(High, fmt.Sprintf("Old Speaker Agreement in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldSpeaker = true
+// Jekyll bundle fails to build
+func checkJekyllBuilds(c *chapterScanT, s string, d fs.DirEntry) error {
+	if config.build {
+		c.printStatus(Info, "Building "+d.Name())
+		cmd := exec.Command("bundle", "install")
+		cmd.Dir = s
+		output, err := cmd.Output()
+		if err != nil {
+			return err
 		}
+		fmt.Fprintf(&c.out, "%s", output)
 
-		if strings.Contains(scanner.Text(), "Conference_Policies") {
-			printStatus(High, fmt.Sprintf("Old conference policy in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldPolicy = true
+		cmd = exec.Command("bundle", "exec jekyll serve")
+		cmd.Dir = s
+		output, err = cmd.Output()
+		if err != nil {
+			return err
 		}
+		fmt.Fprintf(&c.out, "%s", output)
+	}
 
-		if strings.Contains(scanner.Text(), "Local_Chapter_Supporter") {
-			printStatus(High, fmt.Sprintf("Old local chapter supporter policy in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldPolicy = true
-		}
+	return nil
+}
 
-		if strings.Contains(scanner.Text(), "Chapter_Rules") || strings.Contains(scanner.Text(), "Chapter_Handbook") {
-			printStatus(High, fmt.Sprintf("Old local chapter rules or handbook in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldPolicy = true
+// Update git repos
+func updateGit(c *chapterScanT) {
+	if config.gitPull {
+		c.printStatus(Info, "Updating "+c.name)
+		if err := syncRepo(&c.out, c.path, "", ""); err != nil {
+			c.reportError("Unable to update "+c.name, err)
 		}
+	}
+}
 
-		if strings.Contains(scanner.Text(), "index.php/Membership") {
-			printStatus(High, fmt.Sprintf("Old individual membership link in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldLink = true
-		}
+// loadScanConfig loads the rules, template and baseline given by the flags,
+// and returns the kinds of repo to scan
+func loadScanConfig() ([]repoKindT, error) {
+	if err := loadRules(config.rules); err != nil {
+		return nil, err
+	}
 
-		if strings.Contains(scanner.Text(), "index.php/Corporate_Membership") {
-			printStatus(High, fmt.Sprintf("Old corporate membership link in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldLink = true
-		}
+	if err := loadTemplate(config.template); err != nil {
+		return nil, err
+	}
 
-		if strings.Contains(scanner.Text(), "OWASP_Project") {
-			printStatus(Low, fmt.Sprintf("Old projects link in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldLink = true
-		}
+	if err := loadBaseline(config.baseline); err != nil {
+		return nil, err
+	}
 
-		if strings.Contains(scanner.Text(), "About_OWASP") {
-			printStatus(Low, fmt.Sprintf("Old About OWASP link in %s on line %d", filename, line))
-			chapterStatus[currChapter].OldLink = true
-		}
+	var err error
+	activeChecks, err = enabledChecks()
+	if err != nil {
+		return nil, err
+	}
 
-		re := regexp.MustCompile(`docs.google.com/a/.*/forms`)
+	return selectedKinds()
+}
 
-		if strings.Contains(scanner.Text(), "docs.google.com/forms") ||
-			strings.Contains(scanner.Text(), "goo.gl/forms") ||
-			strings.Contains(scanner.Text(), "forms.gle") {
-			printStatus(High, fmt.Sprintf("High: Google Forms link in %s on line %d", filename, line))
-			chapterStatus[currChapter].GoogleForms = unknown
-		}
+// Exit codes, so CI can tell policy violations from the scanner failing
+const (
+	exitOK         = 0
+	exitViolations = 1
+	exitError      = 2
+)
 
-		if re.Match(scanner.Bytes()) && strings.Contains(scanner.Text(), "owasp.org") {
-			printStatus(High, fmt.Sprintf("Info: OWASP Google Forms link in %s on line %d", filename, line))
-			chapterStatus[currChapter].GoogleForms = owasp
-		}
+// fatal stops the scanner when it can't carry on
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitError)
+}
 
-		if re.Match(scanner.Bytes()) && !strings.Contains(scanner.Text(), "owasp.org") {
-			printStatus(Policy, fmt.Sprintf("Non-GDPR Google Forms link in %s on line %d", filename, line))
-			chapterStatus[currChapter].GoogleForms = gdpr_violation
+// exitCode is exitError if any part of the scan failed, as the results are
+// incomplete, otherwise exitViolations if there are findings at or above
+// -fail-on
+func exitCode(scans []*chapterScanT, failOn *StatusLevelT) int {
+	violations := 0
+	for _, c := range scans {
+		if c.errors > 0 {
+			return exitError
 		}
 
-		line++
+		for _, f := range c.status.Findings {
+			if failOn != nil && f.Severity >= *failOn {
+				violations++
+			}
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		printStatus(Info, "checkForOldPolicy error: "+err.Error())
+	if violations > 0 {
+		fmt.Printf("%d findings at or above %s\n", violations, *failOn)
+		return exitViolations
 	}
 
-	return nil
+	return exitOK
 }
 
-// check if not meetup, then we manually look for other platforms (ConnPass, etc)
-func checkNonAutomatedPlatforms(s string, d fs.DirEntry) {
-	// ConnPass, EventBrite, Facebook Groups, etc
+// commands are run with scanner <command> [flags]
+var commands = map[string]func(args []string) error{
+	"diff":          runDiff,
+	"history":       runHistory,
+	"notify-email":  runNotifyEmail,
+	"notify-github": runNotifyGitHub,
+	"propose":       runPropose,
+	"serve":         runServe,
+	"sync":          runSync,
 }
 
-// Tab filename and title metadata is incorrect
-func checkTabTags(s string, d fs.DirEntry) {
-	// find the tag in index.md
-
-	// if no tag, but tab_files exist, display an error and exit
-
-	// search all tab_name.md files for tags
-
-	// show any tabs that aren't tagged correctly
+// outputFile is where the JSON or SARIF output is written, "" for nowhere. A
+// leader's own clone is left as it was, unless they ask for the output.
+func outputFile() string {
+	if config.output == "" && config.path == "" {
+		return "scanner_output." + config.format
+	}
 
+	return config.output
 }
 
-// Jekyll bundle fails to build
-func checkJekyllBuilds(s string, d fs.DirEntry) {
-	if config.build {
-		if d.IsDir() && strings.HasPrefix(d.Name(), "www-chapter") {
-			printStatus(Info, "Building "+d.Name())
-			cmd := exec.Command("bundle", "install")
-			cmd.Dir = s
-			output, err := cmd.Output()
-			cmd.Run()
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Printf("%s", output)
+func main() {
+	config = loadConfig()
 
-			cmd = exec.Command("bundle", "exec jekyll serve")
-			cmd.Dir = s
-			output, err = cmd.Output()
-			cmd.Run()
-			if err != nil {
-				log.Fatal(err)
+	// Subcommands, otherwise scan the repos in chapters/
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fatal(err)
 			}
-			fmt.Printf("%s", output)
-
+			return
 		}
 	}
-}
-
-// Update git repos
-func updateGit(s string, d fs.DirEntry) {
 
-	if config.gitPull {
-		if d.IsDir() && strings.HasPrefix(d.Name(), "www-chapter") {
-			printStatus(Info, "Updating "+d.Name())
-			cmd := exec.Command("git", "pull")
-			cmd.Dir = s
-			output, err := cmd.Output()
-			cmd.Run()
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Printf("%s", output)
-		}
-	}
-}
+	fmt.Println("OWASP Policy Scanner Tool")
 
-var dirsInspected int = 0
+	processFlags()
 
-func walk(s string, d fs.DirEntry, e error) error {
-	if e != nil {
-		return e
+	kinds, err := loadScanConfig()
+	if err != nil {
+		fatal(err)
 	}
 
-	// If we are only processing one chapter, let's only do that one
-	if len(config.chapter) > 0 && !strings.Contains(s, config.chapter) {
-		return nil
+	var failOn *StatusLevelT
+	if config.failOn != "" {
+		sl, err := parseStatusLevel(config.failOn)
+		if err != nil {
+			fatal(fmt.Errorf("-fail-on: %v", err))
+		}
+		failOn = &sl
 	}
 
-	// Directory Checks
-	if d.IsDir() && strings.HasPrefix(d.Name(), "www-chapter") {
-		currChapter = d.Name()
-		blankStatus := &chapterStatusT{}
-		chapterStatus[currChapter] = blankStatus
-		fmt.Println()
-		fmt.Println("Scanning chapter ", currChapter)
-		updateGit(s, d)
-		checkPagesStatus(currChapter)
-		checkIfSite(s, d)
-		checkJekyllBuilds(s, d)
-		dirsInspected++
-	}
-
-	// File Checks
-	if !d.IsDir() {
-		checkLeaderCount(s, d)
-		checkMeetupExists(s, d)
-		checkMeetupMissingMetaData(s, d)
-		checkLeadersInCopper(s, d)
-		checkDefaultMigrationHeader(s, d)
-		checkDefaultText(s, d)
-		checkDefaultExampleTab(s, d)
-		checkConfigYml(s, d)
-		checkForDonate(s, d)
-		checkForOldPolicy(s, d)
-		checkForOldWiki(s, d)
-		checkOldGitIgnore(s, d)
-		checkNonAutomatedPlatforms(s, d)
-		checkTabTags(s, d)
+	if config.listRules {
+		listRules()
+		return
 	}
-	return nil
-}
-
-func main() {
-	fmt.Println("OWASP Policy Scanner Tool")
 
-	chapterStatus = make(map[string]*chapterStatusT)
-
-	config = loadConfig()
-	processFlags()
+	if config.format != "json" && config.format != "sarif" {
+		fatal(fmt.Errorf("unknown -format %q, expected json or sarif", config.format))
+	}
 
 	// client, err := mongo.NewClient(options.Client().ApplyURI(mongoConnUrl))
 	// if err != nil {
@@ -900,11 +835,62 @@ func main() {
 	// 	fmt.Println("Connected to MongoDB")
 	// }
 
-	filepath.WalkDir("chapters/", walk)
+	chapters, err := reposToScan(kinds)
+	if err != nil {
+		fatal(err)
+	}
 
-	if dirsInspected == 0 {
-		fmt.Println("No chapters scanned")
-	} else {
-		writeJSON()
+	scans := scanChapters(chapters, config.jobs)
+
+	if len(scans) == 0 {
+		fmt.Println("No repos scanned")
+		return
+	}
+
+	analysis := analyzeLeaders(scans, config.maxChapters)
+	writeLeaderAnalysis(os.Stdout, analysis)
+
+	// an output that can't be written is as bad as a failed scan
+	writeFailed := false
+
+	output := outputFile()
+	switch {
+	case output == "":
+	case config.format == "sarif":
+		if err := writeSARIF(scans, output); err != nil {
+			println("Error writing SARIF to disk")
+			writeFailed = true
+		}
+
+	default:
+		if err := writeJSON(newScannerOutput(kinds, scans), analysis, newScanInfo(), output); err != nil {
+			writeFailed = true
+		}
+	}
+
+	if config.html != "" {
+		if err := writeHTML(kinds, scans, config.html); err != nil {
+			println("Error writing HTML report to disk")
+			writeFailed = true
+		}
+	}
+
+	if config.history != "" {
+		if err := appendHistory(scans, time.Now(), config.history); err != nil {
+			println("Error writing history to disk")
+			writeFailed = true
+		}
+	}
+
+	if config.writeBaseline != "" {
+		if err := writeBaseline(scans, config.writeBaseline); err != nil {
+			println("Error writing baseline to disk")
+			writeFailed = true
+		}
+	}
+
+	if writeFailed {
+		os.Exit(exitError)
 	}
+	os.Exit(exitCode(scans, failOn))
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testConfig starts the test from the default config, and puts back the
+// config it had when the test ends
+func testConfig(t *testing.T) {
+	saved := config
+	config = loadConfig()
+	config.jobs = 1
+	t.Cleanup(func() { config = saved })
+}
+
+// newTestScan is a scan of a chapter in dir, for testing checks directly
+func newTestScan(name string, dir string) *chapterScanT {
+	return &chapterScanT{name: name, kind: kindOf(name).name, path: dir, status: &chapterStatusT{}}
+}
+
+func findingMessages(findings []Finding) []string {
+	var messages []string
+	for _, f := range findings {
+		messages = append(messages, f.Message)
+	}
+
+	return messages
+}
+
+func assertStrings(t *testing.T, what string, got []string, want []string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %q, want %q", what, got, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	scan := func(errors int, severities ...StatusLevelT) *chapterScanT {
+		c := newTestScan("www-chapter-london", "")
+		c.errors = errors
+		for _, sl := range severities {
+			c.status.Findings = append(c.status.Findings, Finding{Severity: sl})
+		}
+		return c
+	}
+	// a Low and a Medium finding, and a suppressed Policy one
+	findings := scan(0, Low, Medium)
+	findings.baselined = []Finding{{Severity: Policy}}
+	clean := scan(0)
+
+	tests := []struct {
+		failOn string
+		scans  []*chapterScanT
+		want   int
+	}{
+		{"", []*chapterScanT{findings}, exitOK},
+		{"info", []*chapterScanT{findings}, exitViolations},
+		{"low", []*chapterScanT{findings}, exitViolations},
+		{"medium", []*chapterScanT{clean, findings}, exitViolations},
+		{"high", []*chapterScanT{findings}, exitOK},
+		{"policy", []*chapterScanT{findings}, exitOK},
+		{"Policy", []*chapterScanT{scan(0, Policy)}, exitViolations},
+		{"info", []*chapterScanT{clean}, exitOK},
+		// the results are incomplete, whatever was found
+		{"", []*chapterScanT{clean, scan(1)}, exitError},
+		{"low", []*chapterScanT{findings, scan(2)}, exitError},
+	}
+
+	for _, tt := range tests {
+		var failOn *StatusLevelT
+		if tt.failOn != "" {
+			sl, err := parseStatusLevel(tt.failOn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failOn = &sl
+		}
+
+		if got := exitCode(tt.scans, failOn); got != tt.want {
+			t.Errorf("-fail-on %q: exit code = %d, want %d", tt.failOn, got, tt.want)
+		}
+	}
+
+	if _, err := parseStatusLevel("critical"); err == nil {
+		t.Errorf("-fail-on critical was accepted")
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	tests := []struct {
+		path   string
+		output string
+		format string
+		want   string
+	}{
+		{"", "", "json", "scanner_output.json"},
+		{"", "", "sarif", "scanner_output.sarif"},
+		{"", "out.json", "json", "out.json"},
+		// a leader's own clone is left as it was
+		{"www-chapter-london", "", "json", ""},
+		{"www-chapter-london", "", "sarif", ""},
+		{"www-chapter-london", "out.sarif", "sarif", "out.sarif"},
+	}
+
+	for _, tt := range tests {
+		testConfig(t)
+		config.path = tt.path
+		config.output = tt.output
+		config.format = tt.format
+
+		if got := outputFile(); got != tt.want {
+			t.Errorf("-path %q -output %q -format %s: output = %q, want %q", tt.path, tt.output, tt.format, got, tt.want)
+		}
+	}
+}
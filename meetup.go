@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// meetupClientT asks the Meetup GraphQL API about chapter groups. The API
+// needs an OAuth access token, sent as a bearer token.
+// https://www.meetup.com/api/guide/
+type meetupClientT struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// the most recent past events and the next upcoming events kept for each group
+const meetupEventCount = 10
+
+const meetupGroupQuery = `query ($urlname: String!, $first: Int!) {
+  groupByUrlname(urlname: $urlname) {
+    name
+    urlname
+    status
+    memberships {
+      totalCount
+    }
+    organizers: memberships(filter: {roles: [ORGANIZER, COORGANIZER, ASSISTANT_ORGANIZER]}) {
+      edges {
+        node {
+          name
+        }
+      }
+    }
+    upcomingEvents: events(status: ACTIVE, first: $first, sort: ASC) {
+      totalCount
+      edges {
+        node {
+          title
+          dateTime
+          eventUrl
+          rsvps {
+            yesCount
+          }
+        }
+      }
+    }
+    pastEvents: events(status: PAST, first: $first, sort: DESC) {
+      totalCount
+      edges {
+        node {
+          title
+          dateTime
+          eventUrl
+          rsvps {
+            yesCount
+          }
+        }
+      }
+    }
+  }
+}`
+
+type meetupGroupT struct {
+	Name        string `json:"name"`
+	Urlname     string `json:"urlname"`
+	Status      string `json:"status"`
+	Memberships struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"memberships"`
+	Organizers struct {
+		Edges []struct {
+			Node struct {
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"organizers"`
+	UpcomingEvents meetupEventsT `json:"upcomingEvents"`
+	PastEvents     meetupEventsT `json:"pastEvents"`
+}
+
+type meetupEventsT struct {
+	TotalCount int `json:"totalCount"`
+	Edges      []struct {
+		Node struct {
+			Title    string    `json:"title"`
+			DateTime time.Time `json:"dateTime"`
+			EventUrl string    `json:"eventUrl"`
+			Rsvps    struct {
+				YesCount int `json:"yesCount"`
+			} `json:"rsvps"`
+		} `json:"node"`
+	} `json:"edges"`
+}
+
+// meetupEventT is a Meetup event saved in scanner_output.json
+type meetupEventT struct {
+	Title    string
+	DateTime time.Time
+	Going    int // RSVPs saying yes
+	URL      string
+	Past     bool
+}
+
+type meetupErrorT struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+func newMeetupClient() *meetupClientT {
+	return &meetupClientT{
+		url:    config.meetupURL,
+		token:  config.meetupToken,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// query runs a GraphQL query. A response with errors fails, unless every
+// error is NOT_FOUND, which is returned as found == false.
+func (mc *meetupClientT) query(query string, variables map[string]interface{}, out interface{}) (found bool, err error) {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return false, err
+	}
+
+	// Meetup limits the points a token can spend in a minute, so back off once
+	// if we are told to
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("POST", mc.url, bytes.NewReader(payload))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+mc.token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := mc.client.Do(req)
+		if err != nil {
+			return false, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return false, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			if wait < 1 {
+				wait = 1
+			}
+			time.Sleep(time.Duration(wait) * time.Second)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return false, fmt.Errorf("meetup POST %s: %s", req.URL.Path, resp.Status)
+		}
+
+		var m struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []meetupErrorT  `json:"errors"`
+		}
+		if err := json.Unmarshal(body, &m); err != nil {
+			return false, err
+		}
+
+		var messages []string
+		for _, e := range m.Errors {
+			if e.Extensions.Code != "NOT_FOUND" {
+				messages = append(messages, e.Message)
+			}
+		}
+		if len(messages) > 0 {
+			return false, fmt.Errorf("meetup: %s", strings.Join(messages, "; "))
+		}
+		if len(m.Errors) > 0 {
+			return false, nil
+		}
+
+		return true, json.Unmarshal(m.Data, out)
+	}
+}
+
+// group looks up a Meetup group by the name in its URL, returning nil if
+// there is no such group
+func (mc *meetupClientT) group(urlname string) (*meetupGroupT, error) {
+	var data struct {
+		GroupByUrlname *meetupGroupT `json:"groupByUrlname"`
+	}
+
+	found, err := mc.query(meetupGroupQuery, map[string]interface{}{"urlname": urlname, "first": meetupEventCount}, &data)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return data.GroupByUrlname, nil
+}
+
+func (g *meetupGroupT) active() bool {
+	return strings.EqualFold(g.Status, "active")
+}
+
+func (g *meetupGroupT) organizers() []string {
+	var names []string
+	for _, edge := range g.Organizers.Edges {
+		names = appendOnce(names, edge.Node.Name)
+	}
+
+	return names
+}
+
+// events lists the upcoming events, soonest first, then the past events,
+// most recent first
+func (g *meetupGroupT) events() []meetupEventT {
+	var events []meetupEventT
+	add := func(list meetupEventsT, past bool) {
+		for _, edge := range list.Edges {
+			events = append(events, meetupEventT{
+				Title:    edge.Node.Title,
+				DateTime: edge.Node.DateTime,
+				Going:    edge.Node.Rsvps.YesCount,
+				URL:      edge.Node.EventUrl,
+				Past:     past,
+			})
+		}
+	}
+	add(g.UpcomingEvents, false)
+	add(g.PastEvents, true)
+
+	return events
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMeetupT is the Meetup GraphQL API with a few groups:
+//
+//	london  active, with events and organizers
+//	quiet   active, but with only 2 past events
+//	paused  not active
+//	gone    not found
+//	broken  fails with a GraphQL error
+//	busy    rate limited the first time it is asked for
+//
+// Any other group is always rate limited.
+type fakeMeetupT struct {
+	url string
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newFakeMeetup(t *testing.T) *fakeMeetupT {
+	m := &fakeMeetupT{requests: map[string]int{}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Query     string
+			Variables struct {
+				Urlname string
+				First   int
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !strings.Contains(req.Query, "groupByUrlname") || req.Variables.First != meetupEventCount {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		m.mu.Lock()
+		m.requests[req.Variables.Urlname]++
+		requests := m.requests[req.Variables.Urlname]
+		m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Variables.Urlname {
+		case "london":
+			w.Write([]byte(`{"data": {"groupByUrlname": {
+				"name": "OWASP London", "urlname": "london", "status": "ACTIVE",
+				"memberships": {"totalCount": 250},
+				"organizers": {"edges": [{"node": {"name": "Jane"}}, {"node": {"name": "Bob"}}, {"node": {"name": "Jane"}}]},
+				"upcomingEvents": {"totalCount": 1, "edges": [
+					{"node": {"title": "Next", "dateTime": "2030-01-02T18:00:00Z", "eventUrl": "https://meetup.com/london/events/3", "rsvps": {"yesCount": 12}}}]},
+				"pastEvents": {"totalCount": 20, "edges": [
+					{"node": {"title": "Last", "dateTime": "2020-01-02T18:00:00Z", "eventUrl": "https://meetup.com/london/events/2", "rsvps": {"yesCount": 40}}},
+					{"node": {"title": "First", "dateTime": "2019-01-02T18:00:00Z", "eventUrl": "https://meetup.com/london/events/1", "rsvps": {"yesCount": 5}}}]}
+			}}}`))
+		case "quiet":
+			w.Write([]byte(`{"data": {"groupByUrlname": {
+				"name": "OWASP Quiet", "urlname": "quiet", "status": "ACTIVE",
+				"pastEvents": {"totalCount": 2}
+			}}}`))
+		case "paused":
+			w.Write([]byte(`{"data": {"groupByUrlname": {"name": "Paused", "urlname": "paused", "status": "INACTIVE"}}}`))
+		case "gone":
+			w.Write([]byte(`{"data": {"groupByUrlname": null}, "errors": [{"message": "group not found", "extensions": {"code": "NOT_FOUND"}}]}`))
+		case "broken":
+			w.Write([]byte(`{"data": null, "errors": [{"message": "points limit exceeded", "extensions": {"code": "RATE_LIMITED"}}]}`))
+		case "busy":
+			if requests == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"data": {"groupByUrlname": {"name": "Busy", "urlname": "busy", "status": "ACTIVE"}}}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	t.Cleanup(server.Close)
+	m.url = server.URL
+
+	return m
+}
+
+func TestMeetupGroup(t *testing.T) {
+	m := newFakeMeetup(t)
+	client := &meetupClientT{url: m.url, token: "token", client: &http.Client{Timeout: 10 * time.Second}}
+
+	tests := []struct {
+		urlname  string
+		wantName string
+		wantErr  string
+	}{
+		{urlname: "london", wantName: "OWASP London"},
+		{urlname: "paused", wantName: "Paused"},
+		{urlname: "gone"},
+		{urlname: "broken", wantErr: "meetup: points limit exceeded"},
+		{urlname: "busy", wantName: "Busy"},
+		{urlname: "always-busy", wantErr: "429 Too Many Requests"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlname, func(t *testing.T) {
+			group, err := client.group(tt.urlname)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			name := ""
+			if group != nil {
+				name = group.Name
+			}
+			if name != tt.wantName {
+				t.Errorf("group = %+v, want %q", group, tt.wantName)
+			}
+		})
+	}
+
+	// rate limited requests are retried once
+	m.mu.Lock()
+	if m.requests["busy"] != 2 || m.requests["always-busy"] != 2 {
+		t.Errorf("requests = %v, want busy and always-busy asked for twice", m.requests)
+	}
+	m.mu.Unlock()
+
+	bad := &meetupClientT{url: m.url, token: "wrong", client: http.DefaultClient}
+	if _, err := bad.group("london"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err with the wrong token = %v, want a 401 error", err)
+	}
+}
+
+func TestMeetupGroupFields(t *testing.T) {
+	m := newFakeMeetup(t)
+	client := &meetupClientT{url: m.url, token: "token", client: http.DefaultClient}
+
+	group, err := client.group("london")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !group.active() {
+		t.Error("london isn't active")
+	}
+	assertStrings(t, "organizers", group.organizers(), []string{"Jane", "Bob"})
+
+	var titles []string
+	for _, e := range group.events() {
+		titles = append(titles, e.Title)
+	}
+	assertStrings(t, "events", titles, []string{"Next", "Last", "First"})
+
+	next := group.events()[0]
+	want := meetupEventT{Title: "Next", DateTime: time.Date(2030, 1, 2, 18, 0, 0, 0, time.UTC), Going: 12, URL: "https://meetup.com/london/events/3"}
+	if !next.DateTime.Equal(want.DateTime) || next.Going != want.Going || next.URL != want.URL || next.Past {
+		t.Errorf("next event = %+v, want %+v", next, want)
+	}
+	if last := group.events()[1]; !last.Past {
+		t.Errorf("last event = %+v, want it in the past", last)
+	}
+}
+
+func TestCheckMeetupExists(t *testing.T) {
+	tests := []struct {
+		header       string
+		wantStatus   serviceStatusT
+		wantFindings []string
+	}{
+		{"meetup-group: london", active, nil},
+		{"meetup-group: paused", inactive, []string{"Meetup exists, but is disabled for paused"}},
+		{"meetup-group: gone", nonexistant, []string{"Meetup Group does not exist for gone"}},
+		{"meetup-group:", nonexistant, []string{"Meetup-group header is present but blank"}},
+		{"meetup-group:  ", nonexistant, []string{"Meetup-group header is present but blank with whitespace"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			testConfig(t)
+			m := newFakeMeetup(t)
+			config.meetup = true
+			config.meetupURL = m.url
+			config.meetupToken = "token"
+
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "index.md")
+			if err := ioutil.WriteFile(filename, []byte("---\ntitle: London\n"+tt.header+"\n---\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := newTestScan("www-chapter-london", dir)
+			if err := checkMeetupExists(c, filename, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if c.status.Meetup != tt.wantStatus {
+				t.Errorf("Meetup = %v, want %v", c.status.Meetup, tt.wantStatus)
+			}
+			assertStrings(t, "findings", findingMessages(c.status.Findings), tt.wantFindings)
+			for _, f := range c.status.Findings {
+				if f.Line != 3 || f.RuleID != "meetup-exists" {
+					t.Errorf("finding = %+v, want meetup-exists on line 3", f)
+				}
+			}
+
+			if tt.wantStatus == active {
+				s := c.status
+				if s.MeetupName != "london" || s.MeetupMembers != 250 || s.MeetupPastMeetings != 20 || s.MeetupUpcomingMeetings != 1 || len(s.MeetupEvents) != 3 {
+					t.Errorf("status = %+v, want london's members and events", s)
+				}
+				assertStrings(t, "organizers", s.MeetupOrganizers, []string{"Jane", "Bob"})
+			}
+		})
+	}
+}
+
+func TestCheckMeetupExistsNoToken(t *testing.T) {
+	testConfig(t)
+	config.meetup = true
+	config.meetupToken = ""
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "index.md")
+	if err := ioutil.WriteFile(filename, []byte("meetup-group: london\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := checkMeetupExists(newTestScan("www-chapter-london", dir), filename, nil)
+	if err == nil || !strings.Contains(err.Error(), "-meetuptoken") {
+		t.Errorf("err = %v, want a hint about -meetuptoken", err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// eventPlatformT is an event platform chapters use instead of (or as well as)
+// Meetup. group extracts the organizer or group from a link, if the platform
+// has an API we can ask for event counts.
+type eventPlatformT struct {
+	name  string
+	link  *regexp.Regexp
+	group func(m []string) string
+}
+
+var eventPlatforms = []eventPlatformT{
+	{
+		name:  "Eventbrite",
+		link:  regexp.MustCompile(`eventbrite\.[a-z.]+/(o|e)/(?:[\w-]*-)?(\d+)\b`),
+		group: organizerID,
+	},
+	{
+		name:  "Connpass",
+		link:  regexp.MustCompile(`([a-z0-9-]+)\.connpass\.com`),
+		group: connpassGroup,
+	},
+	{name: "Doorkeeper", link: regexp.MustCompile(`[a-z0-9-]+\.doorkeeper\.jp`)},
+	{name: "Facebook", link: regexp.MustCompile(`(facebook\.com|fb\.com)/(groups|events)/`)},
+	{name: "LinkedIn", link: regexp.MustCompile(`linkedin\.com/(groups|events)/`)},
+	{name: "Luma", link: regexp.MustCompile(`lu\.ma/`)},
+	{name: "Peatix", link: regexp.MustCompile(`[a-z0-9-]+\.peatix\.com`)},
+}
+
+// Only Eventbrite organizer pages (/o/) identify the chapter, event pages (/e/) don't
+func organizerID(m []string) string {
+	if m[1] != "o" {
+		return ""
+	}
+	return m[2]
+}
+
+// Connpass groups are subdomains, but www.connpass.com isn't a group
+func connpassGroup(m []string) string {
+	if m[1] == "www" {
+		return ""
+	}
+	return m[1]
+}
+
+// eventGroupT is a chapter's organizer or group on an event platform
+type eventGroupT struct {
+	platform string
+	id       string
+}
+
+// check if not meetup, then we manually look for other platforms (ConnPass, etc)
+func checkNonAutomatedPlatforms(c *chapterScanT, filename string, d fs.DirEntry) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Splits on newlines by default.
+	scanner := bufio.NewScanner(f)
+
+	line := 1
+	// https://golang.org/pkg/bufio/#Scanner.Scan
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		// ConnPass, EventBrite, Facebook Groups, etc
+		for _, p := range eventPlatforms {
+			m := p.link.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+
+			if !contains(c.status.Platforms, p.name) {
+				c.status.Platforms = append(c.status.Platforms, p.name)
+				sort.Strings(c.status.Platforms)
+				c.printStatus(Info, fmt.Sprintf("%s link found in %s on line %d", p.name, filename, line))
+			}
+
+			if p.group == nil {
+				continue
+			}
+
+			group := eventGroupT{platform: p.name, id: p.group(m)}
+			if group.id != "" && !containsGroup(c.eventGroups, group) {
+				c.eventGroups = append(c.eventGroups, group)
+			}
+		}
+
+		line++
+	}
+
+	return scanner.Err()
+}
+
+func containsGroup(groups []eventGroupT, group eventGroupT) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPlatformEvents asks the platforms with public APIs how many events the
+// chapter has held, once all the links in the chapter have been found. The
+// links are found without -platforms, as that makes no API calls.
+func checkPlatformEvents(c *chapterScanT) error {
+	if !config.platforms {
+		return nil
+	}
+
+	for _, group := range c.eventGroups {
+		var past, upcoming int
+		var err error
+
+		switch group.platform {
+		case "Eventbrite":
+			past, upcoming, err = eventbriteEventCounts(group.id)
+		case "Connpass":
+			past, upcoming, err = connpassEventCounts(group.id)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		c.status.PlatformPastEvents += past
+		c.status.PlatformUpcomingEvents += upcoming
+
+		c.printStatus(Info, fmt.Sprintf("%s %s, %d upcoming events, %d past events", group.platform, group.id, upcoming, past))
+	}
+
+	return nil
+}
+
+// Too few past events, counting Meetup and the other platforms together, so a
+// chapter isn't reported for a quiet platform when it meets on another. It
+// needs the Meetup counts from -meetup, the other platforms are only counted
+// with -platforms.
+func checkEventActivity(c *chapterScanT) error {
+	if !config.meetup {
+		return nil
+	}
+
+	var sources []string
+	past, upcoming := 0, 0
+
+	if c.status.Meetup == active {
+		sources = append(sources, "Meetup "+c.status.MeetupName)
+		past += c.status.MeetupPastMeetings
+		upcoming += c.status.MeetupUpcomingMeetings
+	}
+	if config.platforms {
+		for _, group := range c.eventGroups {
+			sources = append(sources, group.platform+" "+group.id)
+		}
+		past += c.status.PlatformPastEvents
+		upcoming += c.status.PlatformUpcomingEvents
+	}
+
+	// a missing or disabled Meetup group is reported by meetup-exists
+	if len(sources) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d upcoming events, %d past events on %s", upcoming, past, strings.Join(sources, ", "))
+	if past < 3 {
+		c.reportFinding(Finding{
+			RuleID:   "event-activity",
+			Severity: Policy,
+			Message:  "Low past meetings. " + msg,
+		})
+	}
+
+	return nil
+}
+
+func getJSON(reqUrl string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", req.URL.Path, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+type eventbriteEventsRespT struct {
+	Pagination struct {
+		Object_count int `json:"object_count"`
+	} `json:"pagination"`
+}
+
+// https://www.eventbrite.com/platform/api#/reference/event/list/list-events-by-organizer
+func eventbriteEventCounts(organizer string) (past int, upcoming int, err error) {
+	headers := map[string]string{"Authorization": "Bearer " + config.eventbriteToken}
+
+	counts := map[string]*int{"past": &past, "current_future": &upcoming}
+	for filter, count := range counts {
+		reqUrl := fmt.Sprintf("%s/organizers/%s/events/?time_filter=%s", strings.TrimSuffix(config.eventbriteURL, "/"), url.PathEscape(organizer), filter)
+
+		var m eventbriteEventsRespT
+		if err := getJSON(reqUrl, headers, &m); err != nil {
+			return 0, 0, err
+		}
+		*count = m.Pagination.Object_count
+	}
+
+	return past, upcoming, nil
+}
+
+type connpassGroupsRespT struct {
+	Groups []struct {
+		ID int `json:"id"`
+	} `json:"groups"`
+}
+
+type connpassEventsRespT struct {
+	Results_available int `json:"results_available"`
+	Events            []struct {
+		Started_at time.Time `json:"started_at"`
+	} `json:"events"`
+}
+
+// https://connpass.com/about/api/v2/
+func connpassEventCounts(subdomain string) (past int, upcoming int, err error) {
+	base := strings.TrimSuffix(config.connpassURL, "/")
+	headers := map[string]string{"X-API-Key": config.connpassKey}
+
+	var groups connpassGroupsRespT
+	err = getJSON(base+"/groups/?subdomain="+url.QueryEscape(subdomain), headers, &groups)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(groups.Groups) == 0 {
+		return 0, 0, fmt.Errorf("connpass group %s not found", subdomain)
+	}
+
+	// newest first, so the upcoming events are all in the first page
+	var events connpassEventsRespT
+	err = getJSON(fmt.Sprintf("%s/events/?group_id=%d&order=2&count=100", base, groups.Groups[0].ID), headers, &events)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	for _, e := range events.Events {
+		if e.Started_at.After(now) {
+			upcoming++
+		}
+	}
+
+	return events.Results_available - upcoming, upcoming, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckEventActivity(t *testing.T) {
+	tests := []struct {
+		name      string
+		group     string
+		platforms bool
+		// past Eventbrite events of the chapter's organizer, if it has one
+		eventbrite   int
+		wantFindings []string
+	}{
+		{"quiet Meetup", "quiet", false, -1, []string{"Low past meetings. 0 upcoming events, 2 past events on Meetup quiet"}},
+		{"busy Meetup", "london", false, -1, nil},
+		{"disabled Meetup", "paused", false, -1, nil},
+		{"Eventbrite not asked", "quiet", false, 5, []string{"Low past meetings. 0 upcoming events, 2 past events on Meetup quiet"}},
+		{"busy Eventbrite", "quiet", true, 5, nil},
+		{"quiet Eventbrite", "quiet", true, 0, []string{"Low past meetings. 0 upcoming events, 2 past events on Meetup quiet, Eventbrite 1234"}},
+		{"quiet Meetup without Eventbrite", "quiet", true, -1, []string{"Low past meetings. 0 upcoming events, 2 past events on Meetup quiet"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfig(t)
+			m := newFakeMeetup(t)
+			config.meetup = true
+			config.meetupURL = m.url
+			config.meetupToken = "token"
+			config.platforms = tt.platforms
+
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "index.md")
+			if err := ioutil.WriteFile(filename, []byte("meetup-group: "+tt.group+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := newTestScan("www-chapter-london", dir)
+			if err := checkMeetupExists(c, filename, nil); err != nil {
+				t.Fatal(err)
+			}
+			c.status.Findings = nil
+
+			// as counted by checkPlatformEvents, which asks Eventbrite only with -platforms
+			if tt.eventbrite >= 0 {
+				c.eventGroups = []eventGroupT{{platform: "Eventbrite", id: "1234"}}
+				if tt.platforms {
+					c.status.PlatformPastEvents = tt.eventbrite
+				}
+			}
+
+			if err := checkEventActivity(c); err != nil {
+				t.Fatal(err)
+			}
+			assertStrings(t, "findings", findingMessages(c.status.Findings), tt.wantFindings)
+		})
+	}
+}
+
+func TestEventActivityNeedsMeetup(t *testing.T) {
+	tests := []struct {
+		meetup    bool
+		platforms bool
+		want      bool
+	}{
+		{false, false, false},
+		{true, false, true},
+		{false, true, false},
+		{true, true, true},
+	}
+
+	for _, tt := range tests {
+		testConfig(t)
+		config.meetup = tt.meetup
+		config.platforms = tt.platforms
+		if got := contains(ranRules(registry), "event-activity"); got != tt.want {
+			t.Errorf("-meetup=%v -platforms=%v: event-activity ran = %v, want %v", tt.meetup, tt.platforms, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNonAutomatedPlatforms(t *testing.T) {
+	testConfig(t)
+	// the links are found without asking the platforms
+	config.platforms = false
+	config.eventbriteURL = "http://127.0.0.1:0"
+	config.connpassURL = "http://127.0.0.1:0"
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "index.md")
+	page := "[Tickets](https://www.eventbrite.co.uk/o/owasp-london-1234)\n" +
+		"[Talk](https://www.eventbrite.com/e/owasp-night-5678)\n" +
+		"[Connpass](https://owasp-tokyo.connpass.com/event/1/)\n" +
+		"[Facebook](https://www.facebook.com/groups/owasp)\n"
+	if err := ioutil.WriteFile(filename, []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestScan("www-chapter-london", dir)
+	if err := checkNonAutomatedPlatforms(c, filename, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPlatformEvents(c); err != nil {
+		t.Fatal(err)
+	}
+
+	assertStrings(t, "platforms", c.status.Platforms, []string{"Connpass", "Eventbrite", "Facebook"})
+	want := []eventGroupT{{"Eventbrite", "1234"}, {"Connpass", "owasp-tokyo"}}
+	if !reflect.DeepEqual(c.eventGroups, want) {
+		t.Errorf("event groups = %+v, want %+v", c.eventGroups, want)
+	}
+	if c.status.PlatformPastEvents != 0 || c.status.PlatformUpcomingEvents != 0 {
+		t.Errorf("counted %d past and %d upcoming events without -platforms", c.status.PlatformPastEvents, c.status.PlatformUpcomingEvents)
+	}
+	if !contains(ranRules(registry), "non-automated-platforms") {
+		t.Errorf("non-automated-platforms didn't run without -platforms")
+	}
+}
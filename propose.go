@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// proposalT is the branch of fixes proposed for one repo
+type proposalT struct {
+	name    string
+	out     bytes.Buffer
+	commits []proposalCommitT
+	err     error
+}
+
+// proposalCommitT is a commit fixing the findings of one rule
+type proposalCommitT struct {
+	check    Check
+	findings []Finding
+}
+
+// unfixed hides the fix of a check, to scan for what the fix left behind
+type unfixed struct {
+	Check
+}
+
+func (u unfixed) Finish(c *chapterScanT) error {
+	if finisher, ok := u.Check.(chapterFinisher); ok {
+		return finisher.Finish(c)
+	}
+
+	return nil
+}
+
+// runPropose creates a -branch in each repo under chapters/ with a commit for
+// each rule whose findings can be fixed, and writes a pull request
+// description for each branch into -pr-dir. Nothing is pushed. It accepts all
+// the scan flags, so -enable, -disable and -chapter choose what is proposed.
+func runPropose(args []string) error {
+	branch := "scanner-fixes"
+	dir := "proposals"
+
+	registerFlags()
+	flag.StringVar(&branch, "branch", branch, "Branch to create in each repo, replacing any earlier proposal with the same name")
+	flag.StringVar(&dir, "pr-dir", dir, "Directory to write a pull request description for each branch into")
+	flag.CommandLine.Parse(args)
+
+	if config.gitPull {
+		return fmt.Errorf("-gitpull can't be used with propose, update the repos with scanner sync first")
+	}
+	if config.path != "" {
+		return fmt.Errorf("-path can't be used with propose, which works on the repos in chapters/")
+	}
+	config.fix = true
+	config.dryRun = false
+
+	kinds, err := loadScanConfig()
+	if err != nil {
+		return err
+	}
+
+	var fixers []Check
+	for _, check := range activeChecks {
+		if fixer, ok := check.(Fixer); ok && fixer.Fixable() {
+			fixers = append(fixers, check)
+		}
+	}
+	if len(fixers) == 0 {
+		return fmt.Errorf("none of the enabled rules can fix their findings")
+	}
+
+	chapters, err := discoverChapters("chapters/", kinds)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	proposals := make([]*proposalT, len(chapters))
+	forEachOrdered(len(chapters), config.jobs, func(i int) {
+		proposals[i] = proposeFixes(chapters[i], fixers, branch, dir)
+	}, func(i int) {
+		os.Stdout.Write(proposals[i].out.Bytes())
+	})
+
+	proposed, failed := 0, 0
+	for _, p := range proposals {
+		if p.err != nil {
+			failed++
+		} else if len(p.commits) > 0 {
+			proposed++
+		}
+	}
+
+	fmt.Printf("\nProposed %d branches, %d repos had nothing to fix, %d failed\n", proposed, len(proposals)-proposed-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d repos failed", failed)
+	}
+
+	return nil
+}
+
+// proposeFixes commits the fixes for each rule to a new branch, and returns
+// the repo to the branch it was on
+func proposeFixes(chapter chapterDirT, fixers []Check, branch string, dir string) *proposalT {
+	p := &proposalT{name: chapter.name}
+
+	fmt.Fprintln(&p.out)
+	fmt.Fprintln(&p.out, "Proposing fixes for "+chapter.kind+" ", chapter.name)
+
+	p.err = p.commitFixes(chapter, fixers, branch)
+	if p.err != nil {
+		writeStatus(&p.out, Info, "Unable to propose fixes for "+chapter.name+": "+p.err.Error())
+		return p
+	}
+
+	if len(p.commits) == 0 {
+		writeStatus(&p.out, Info, "Nothing to fix")
+		return p
+	}
+
+	filename := filepath.Join(dir, chapter.name+".md")
+	if p.err = ioutil.WriteFile(filename, []byte(p.description(branch)), 0644); p.err != nil {
+		writeStatus(&p.out, Info, "Unable to write the pull request description: "+p.err.Error())
+		return p
+	}
+
+	writeStatus(&p.out, Info, fmt.Sprintf("Branch %s has %d commits, see %s", branch, len(p.commits), filename))
+	return p
+}
+
+func (p *proposalT) commitFixes(chapter chapterDirT, fixers []Check, branch string) error {
+	status, err := git(chapter.path, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("%s has uncommitted changes", chapter.path)
+	}
+
+	// the branch the repo is on, or the commit if it is detached
+	original, err := git(chapter.path, "symbolic-ref", "-q", "--short", "HEAD")
+	if err != nil {
+		if original, err = git(chapter.path, "rev-parse", "HEAD"); err != nil {
+			return err
+		}
+	}
+	original = strings.TrimSpace(original)
+
+	// fixes are always made on top of the default branch, so a proposal
+	// doesn't include the earlier one or the leader's own work
+	base, err := git(chapter.path, "rev-parse", "--verify", "-q", "origin/HEAD")
+	if err != nil {
+		return fmt.Errorf("%s has no origin/HEAD to propose fixes to, try git remote set-head origin -a", chapter.path)
+	}
+
+	// the commits are made on a detached HEAD, and only become the branch if
+	// there are any, so an earlier proposal is kept when there is nothing new
+	if _, err := git(chapter.path, "checkout", "-q", "--detach", strings.TrimSpace(base)); err != nil {
+		return err
+	}
+
+	err = p.commitEachFix(chapter, fixers)
+
+	if err != nil {
+		git(chapter.path, "reset", "-q", "--hard")
+	} else if len(p.commits) > 0 {
+		_, err = git(chapter.path, "branch", "-f", branch, "HEAD")
+	}
+	if _, cerr := git(chapter.path, "checkout", "-q", original); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (p *proposalT) commitEachFix(chapter chapterDirT, fixers []Check) error {
+	for _, check := range fixers {
+		c := scanChapterWith(chapter, []Check{check})
+		if c.errors > 0 {
+			p.out.Write(c.out.Bytes())
+			return fmt.Errorf("%s failed", check.ID())
+		}
+		if len(c.status.Fixed) == 0 {
+			continue
+		}
+
+		// what is still found once the fix is made wasn't fixed
+		after := scanChapterWith(chapter, []Check{unfixed{check}})
+		remaining := map[string]bool{}
+		for _, f := range append(after.status.Findings, after.baselined...) {
+			remaining[f.Fingerprint] = true
+		}
+
+		var fixed []Finding
+		for _, f := range append(c.status.Findings, c.baselined...) {
+			if !remaining[f.Fingerprint] {
+				fixed = append(fixed, f)
+			}
+		}
+
+		commit := proposalCommitT{check: check, findings: fixed}
+		if _, err := git(chapter.path, "add", "-A"); err != nil {
+			return err
+		}
+		if _, err := git(chapter.path, "commit", "-q", "-m", commit.message()); err != nil {
+			return err
+		}
+
+		p.commits = append(p.commits, commit)
+		writeStatus(&p.out, Info, fmt.Sprintf("Committed %s, fixing %d findings in %s", check.ID(), len(fixed), strings.Join(c.status.Fixed, ", ")))
+	}
+
+	return nil
+}
+
+func (commit proposalCommitT) message() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Fix %s (%s)\n\n", commit.check.Description(), commit.check.ID())
+	fmt.Fprintln(&b, "Fixed by the OWASP policy scanner:")
+	fmt.Fprintln(&b)
+	for _, f := range commit.findings {
+		fmt.Fprintf(&b, "- %s\n", f.Message)
+	}
+
+	return b.String()
+}
+
+// description is the pull request description for the branch. The first line
+// is the title.
+func (p *proposalT) description(branch string) string {
+	total := 0
+	for _, commit := range p.commits {
+		total += len(commit.findings)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Fix OWASP policy scanner findings in %s\n\n", p.name)
+	fmt.Fprintf(&b, "The %s branch fixes %d findings reported by the OWASP policy scanner, with a commit for each rule.\n", branch, total)
+	for _, commit := range p.commits {
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", commit.check.Description(), commit.check.ID())
+		for _, f := range commit.findings {
+			fmt.Fprintf(&b, "- %s\n", f.Message)
+		}
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "These changes were made mechanically by `scanner propose`. Please check the pages still read well before merging.")
+
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setGitIdentity gives propose's commits an author, as it commits with the
+// user's own git identity
+func setGitIdentity(t *testing.T) {
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		saved, ok := os.LookupEnv(name)
+		value := "Test"
+		if strings.HasSuffix(name, "EMAIL") {
+			value = "test@example.com"
+		}
+		os.Setenv(name, value)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(name, saved)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func fixersFor(t *testing.T, ids ...string) []Check {
+	t.Helper()
+	var fixers []Check
+	for _, id := range ids {
+		for _, c := range registry {
+			if c.ID() == id {
+				fixers = append(fixers, c)
+			}
+		}
+	}
+	if len(fixers) != len(ids) {
+		t.Fatalf("fixers = %v, want %v", fixers, ids)
+	}
+
+	return fixers
+}
+
+func TestProposeFixes(t *testing.T) {
+	testConfig(t)
+	setGitIdentity(t)
+	config.fix = true
+
+	remote := t.TempDir()
+	work := newBareRepo(t, remote, "OWASP", "www-chapter-london", "main")
+	commitFile(t, work, "tab_example.md", "example\n")
+	commitFile(t, work, ".gitignore", "_site\n")
+	testGit(t, work, "push", "--quiet", "origin", "main")
+
+	clone := filepath.Join(t.TempDir(), "chapters", "www-chapter-london")
+	testGit(t, "", "clone", "--quiet", filepath.Join(remote, "OWASP", "www-chapter-london.git"), clone)
+	chapter := chapterDirT{name: "www-chapter-london", kind: "chapter", path: clone}
+	fixers := fixersFor(t, "example-tab", "old-gitignore")
+	prDir := t.TempDir()
+
+	// the leader's own work isn't part of the proposal
+	testGit(t, clone, "checkout", "--quiet", "-b", "leader-work")
+	commitFile(t, clone, "leader.md", "mine\n")
+
+	p := proposeFixes(chapter, fixers, "scanner-fixes", prDir)
+	if p.err != nil {
+		t.Fatal(p.err)
+	}
+	if len(p.commits) != 2 {
+		t.Fatalf("%d commits, want one for each rule", len(p.commits))
+	}
+
+	if got, want := testGit(t, clone, "rev-parse", "scanner-fixes~2"), testGit(t, clone, "rev-parse", "origin/HEAD"); got != want {
+		t.Errorf("scanner-fixes is based on %s, want origin/HEAD %s", got, want)
+	}
+	files := strings.Fields(testGit(t, clone, "ls-tree", "-r", "--name-only", "scanner-fixes"))
+	assertStrings(t, "files on scanner-fixes", files, []string{".gitignore", "README.md"})
+	if got := testGit(t, clone, "show", "scanner-fixes:.gitignore"); got != "_site\nGemfile.lock" {
+		t.Errorf(".gitignore = %q, want Gemfile.lock added", got)
+	}
+	if got := testGit(t, clone, "log", "-1", "--format=%s", "scanner-fixes~1"); got != "Fix Default tab tab_example.md is present (example-tab)" {
+		t.Errorf("first commit = %q, want the example-tab fix", got)
+	}
+
+	// the repo is left as it was
+	if got := testGit(t, clone, "rev-parse", "--abbrev-ref", "HEAD"); got != "leader-work" {
+		t.Errorf("branch = %q, want leader-work", got)
+	}
+	if got := readFile(t, filepath.Join(clone, "tab_example.md")); got != "example\n" {
+		t.Errorf("tab_example.md = %q, want it left alone", got)
+	}
+	if got := testGit(t, clone, "status", "--porcelain"); got != "" {
+		t.Errorf("status = %q, want a clean checkout", got)
+	}
+
+	description := readFile(t, filepath.Join(prDir, "www-chapter-london.md"))
+	if !strings.HasPrefix(description, "# Fix OWASP policy scanner findings in www-chapter-london\n") {
+		t.Errorf("description = %q", description)
+	}
+
+	// once the default branch is fixed there is nothing new to propose, and
+	// the earlier proposal is kept
+	earlier := testGit(t, clone, "rev-parse", "scanner-fixes")
+	testGit(t, work, "rm", "--quiet", "tab_example.md")
+	commitFile(t, work, ".gitignore", "_site\nGemfile.lock\n")
+	testGit(t, work, "push", "--quiet", "origin", "main")
+	testGit(t, clone, "fetch", "--quiet", "origin")
+
+	p = proposeFixes(chapter, fixers, "scanner-fixes", prDir)
+	if p.err != nil {
+		t.Fatal(p.err)
+	}
+	if len(p.commits) != 0 {
+		t.Errorf("%d commits, want nothing left to fix", len(p.commits))
+	}
+	if got := testGit(t, clone, "rev-parse", "scanner-fixes"); got != earlier {
+		t.Errorf("scanner-fixes = %s, want the earlier proposal %s", got, earlier)
+	}
+	if got := testGit(t, clone, "rev-parse", "--abbrev-ref", "HEAD"); got != "leader-work" {
+		t.Errorf("branch = %q, want leader-work", got)
+	}
+}
+
+func TestProposeFixesRefuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, clone string)
+		wantErr string
+	}{
+		{"uncommitted changes", func(t *testing.T, clone string) {
+			if err := ioutil.WriteFile(filepath.Join(clone, "index.md"), []byte("draft\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "has uncommitted changes"},
+		{"no origin/HEAD", func(t *testing.T, clone string) {
+			testGit(t, clone, "remote", "set-head", "origin", "-d")
+		}, "has no origin/HEAD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfig(t)
+			setGitIdentity(t)
+			config.fix = true
+
+			remote := t.TempDir()
+			work := newBareRepo(t, remote, "OWASP", "www-chapter-london", "main")
+			commitFile(t, work, "tab_example.md", "example\n")
+			testGit(t, work, "push", "--quiet", "origin", "main")
+
+			clone := filepath.Join(t.TempDir(), "www-chapter-london")
+			testGit(t, "", "clone", "--quiet", filepath.Join(remote, "OWASP", "www-chapter-london.git"), clone)
+			tt.prepare(t, clone)
+
+			chapter := chapterDirT{name: "www-chapter-london", kind: "chapter", path: clone}
+			p := proposeFixes(chapter, fixersFor(t, "example-tab"), "scanner-fixes", t.TempDir())
+			if p.err == nil || !strings.Contains(p.err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", p.err, tt.wantErr)
+			}
+			if _, err := git(clone, "rev-parse", "--verify", "-q", "scanner-fixes"); err == nil {
+				t.Errorf("scanner-fixes was created")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"time"
+)
+
+// The HTML report template. Everything it needs, including the JavaScript to
+// sort and filter the tables, is inline so the report is a single file.
+//
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+// The report's severity columns, most serious first
+var reportSeverities = []StatusLevelT{Policy, High, Medium, Low, Info}
+
+type reportT struct {
+	Generated  string
+	Kinds      []string
+	Severities []StatusLevelT
+	Repos      []reportRepoT
+}
+
+type reportRepoT struct {
+	Name     string
+	Kind     string
+	URL      string
+	Leaders  int
+	Meetup   string
+	GitHub   string
+	Counts   []int // findings per reportSeverities
+	Findings []reportFindingT
+}
+
+type reportFindingT struct {
+	Finding
+	Link string
+}
+
+func (s serviceStatusT) String() string {
+	switch s {
+	case nonexistant:
+		return "nonexistent"
+	case inactive:
+		return "inactive"
+	case active:
+		return "active"
+	}
+
+	return fmt.Sprintf("serviceStatusT(%d)", int(s))
+}
+
+// repoURL is the repo on GitHub
+func repoURL(name string) string {
+	return fmt.Sprintf("https://github.com/%s/%s", config.org, name)
+}
+
+// fileURL links to the line of the file on the repo's default branch
+func fileURL(name string, file string, line int) string {
+	u := repoURL(name) + "/blob/HEAD/" + file
+	if line > 0 {
+		u += fmt.Sprintf("#L%d", line)
+	}
+
+	return u
+}
+
+// serviceStatus is how the report shows a status that is only checked when
+// the flag is given
+func serviceStatus(checked bool, s serviceStatusT) string {
+	if !checked {
+		return "not checked"
+	}
+
+	return s.String()
+}
+
+// writeHTML writes the summary table and each repo's findings as a single
+// static HTML file
+func writeHTML(kinds []repoKindT, scans []*chapterScanT, filename string) error {
+	report := reportT{
+		Generated:  time.Now().Format("2006-01-02 15:04"),
+		Severities: reportSeverities,
+	}
+
+	for _, k := range kinds {
+		report.Kinds = append(report.Kinds, k.name)
+	}
+
+	for _, c := range scans {
+		repo := reportRepoT{
+			Name:    c.name,
+			Kind:    c.kind,
+			URL:     repoURL(c.name),
+			Leaders: c.status.Leaders,
+			Meetup:  serviceStatus(config.meetup, c.status.Meetup),
+			GitHub:  serviceStatus(config.pages, c.status.GitHub),
+			Counts:  make([]int, len(reportSeverities)),
+		}
+
+		for _, f := range c.status.Findings {
+			for i, sl := range reportSeverities {
+				if f.Severity == sl {
+					repo.Counts[i]++
+				}
+			}
+
+			rf := reportFindingT{Finding: f}
+			if f.File != "" {
+				rf.Link = fileURL(c.name, f.File, f.Line)
+			}
+			repo.Findings = append(repo.Findings, rf)
+		}
+
+		report.Repos = append(report.Repos, repo)
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, report); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The built in rules, used unless -rules is given
+//
+//go:embed rules.yml
+var defaultRules []byte
+
+// patternRuleT is a declarative link-and-text rule loaded from a rules file.
+// See rules.yml for a description of each field.
+type patternRuleT struct {
+	RuleID       string       `yaml:"id"`
+	Desc         string       `yaml:"description"`
+	Level        StatusLevelT `yaml:"severity"`
+	RepoKinds    []string     `yaml:"kinds"`
+	Files        string       `yaml:"files"`
+	ExcludeFiles []string     `yaml:"exclude_files"`
+	Contains     []string     `yaml:"contains"`
+	Regex        string       `yaml:"regex"`
+	Requires     []string     `yaml:"requires"`
+	Excludes     []string     `yaml:"excludes"`
+	Message      string       `yaml:"message"`
+	Status       string       `yaml:"status"`
+	Value        string       `yaml:"value"`
+	FixRegex     string       `yaml:"fix"`
+	Replace      string       `yaml:"replace"`
+
+	re      *regexp.Regexp
+	fixRe   *regexp.Regexp
+	message *template.Template
+}
+
+type rulesFileT struct {
+	Rules []*patternRuleT `yaml:"rules"`
+}
+
+// The values a rule can set on the enumerated chapterStatusT fields
+var statusValues = map[reflect.Type]map[string]int64{
+	reflect.TypeOf(privacyStatusT(0)): {
+		"notpresent":     int64(notpresent),
+		"unknown":        unknown,
+		"owasp":          owasp,
+		"gdpr_violation": gdpr_violation,
+	},
+	reflect.TypeOf(serviceStatusT(0)): {
+		"nonexistant": int64(nonexistant),
+		"inactive":    inactive,
+		"active":      active,
+	},
+}
+
+func (r *patternRuleT) ID() string             { return r.RuleID }
+func (r *patternRuleT) Description() string    { return r.Desc }
+func (r *patternRuleT) Severity() StatusLevelT { return r.Level }
+func (r *patternRuleT) Kinds() []string        { return r.RepoKinds }
+
+func (r *patternRuleT) Matches(path string, d fs.DirEntry) bool {
+	if d.IsDir() {
+		return false
+	}
+
+	for _, exclude := range r.ExcludeFiles {
+		if d.Name() == exclude {
+			return false
+		}
+	}
+
+	matched, _ := filepath.Match(r.Files, d.Name())
+	return matched
+}
+
+// match returns the text on the line that triggered the rule, if any
+func (r *patternRuleT) match(text string) (string, bool) {
+	found := ""
+	if r.re != nil {
+		found = r.re.FindString(text)
+	}
+
+	for _, literal := range r.Contains {
+		if found != "" {
+			break
+		}
+		if strings.Contains(text, literal) {
+			found = literal
+		}
+	}
+
+	if found == "" {
+		return "", false
+	}
+
+	for _, required := range r.Requires {
+		if !strings.Contains(text, required) {
+			return "", false
+		}
+	}
+
+	for _, excluded := range r.Excludes {
+		if strings.Contains(text, excluded) {
+			return "", false
+		}
+	}
+
+	return found, true
+}
+
+func (r *patternRuleT) Run(c *chapterScanT, filename string, d fs.DirEntry) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Splits on newlines by default.
+	scanner := bufio.NewScanner(f)
+
+	line := 1
+	// https://golang.org/pkg/bufio/#Scanner.Scan
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		if found, ok := r.match(text); ok {
+			var msg bytes.Buffer
+			err := r.message.Execute(&msg, map[string]interface{}{
+				"Path":  filename,
+				"File":  repoRelative(c.path, filename),
+				"Line":  line,
+				"Match": found,
+			})
+			if err != nil {
+				return err
+			}
+
+			c.reportFinding(Finding{
+				RuleID:   r.RuleID,
+				Severity: r.Level,
+				File:     filename,
+				Line:     line,
+				Column:   column(text, found),
+				Message:  msg.String(),
+				Snippet:  text,
+			})
+			r.setStatus(c.status)
+		}
+
+		line++
+	}
+
+	return scanner.Err()
+}
+
+func (r *patternRuleT) Fixable() bool { return r.fixRe != nil }
+
+// Fix replaces the fix regex on each line the rule matches
+func (r *patternRuleT) Fix(c *chapterScanT, filename string, d fs.DirEntry) error {
+	if r.fixRe == nil {
+		return nil
+	}
+
+	return c.fixLines(r.RuleID, filename, func(line string) string {
+		if _, ok := r.match(line); !ok {
+			return line
+		}
+		return r.fixRe.ReplaceAllString(line, r.Replace)
+	})
+}
+
+func (r *patternRuleT) setStatus(status *chapterStatusT) {
+	if r.Status == "" {
+		return
+	}
+
+	field := reflect.ValueOf(status).Elem().FieldByName(r.Status)
+	if field.Kind() == reflect.Bool {
+		field.SetBool(true)
+		return
+	}
+
+	field.SetInt(statusValues[field.Type()][r.Value])
+}
+
+// compile checks the rule is complete and prepares it for use
+func (r *patternRuleT) compile() error {
+	if r.RuleID == "" {
+		return fmt.Errorf("rule has no id")
+	}
+
+	if len(r.Contains) == 0 && r.Regex == "" {
+		return fmt.Errorf("rule %s has neither contains nor regex", r.RuleID)
+	}
+
+	for _, kind := range r.RepoKinds {
+		if findKind(kind) == nil {
+			return fmt.Errorf("rule %s: unknown kind %q, expected %s", r.RuleID, kind, kindNames())
+		}
+	}
+
+	if r.Files == "" {
+		r.Files = "*.md"
+	}
+	if _, err := filepath.Match(r.Files, ""); err != nil {
+		return fmt.Errorf("rule %s: bad files glob: %v", r.RuleID, err)
+	}
+
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("rule %s: %v", r.RuleID, err)
+		}
+		r.re = re
+	}
+
+	if r.FixRegex != "" {
+		re, err := regexp.Compile(r.FixRegex)
+		if err != nil {
+			return fmt.Errorf("rule %s: fix: %v", r.RuleID, err)
+		}
+		r.fixRe = re
+	} else if r.Replace != "" {
+		return fmt.Errorf("rule %s has replace but no fix", r.RuleID)
+	}
+
+	if r.Message == "" {
+		r.Message = r.Desc + " in {{.Path}} on line {{.Line}}"
+	}
+	message, err := template.New(r.RuleID).Parse(r.Message)
+	if err != nil {
+		return fmt.Errorf("rule %s: %v", r.RuleID, err)
+	}
+	r.message = message
+
+	if r.Status != "" {
+		field, ok := reflect.TypeOf(chapterStatusT{}).FieldByName(r.Status)
+		if !ok {
+			return fmt.Errorf("rule %s: unknown status field %s", r.RuleID, r.Status)
+		}
+
+		if field.Type.Kind() != reflect.Bool {
+			values, ok := statusValues[field.Type]
+			if !ok {
+				return fmt.Errorf("rule %s: status field %s can't be set by a rule", r.RuleID, r.Status)
+			}
+			if _, ok := values[r.Value]; !ok {
+				return fmt.Errorf("rule %s: unknown value %q for status field %s", r.RuleID, r.Value, r.Status)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseRules reads a YAML (or JSON) rules file
+func parseRules(data []byte) ([]*patternRuleT, error) {
+	var file rulesFileT
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, c := range registry {
+		seen[c.ID()] = true
+	}
+
+	for _, r := range file.Rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+		if seen[r.RuleID] {
+			return nil, fmt.Errorf("duplicate rule id %s", r.RuleID)
+		}
+		seen[r.RuleID] = true
+	}
+
+	return file.Rules, nil
+}
+
+// loadRules registers the pattern rules from filename, or the built in rules
+// if filename is empty
+func loadRules(filename string) error {
+	data := defaultRules
+	if filename != "" {
+		var err error
+		data, err = ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	rules, err := parseRules(data)
+	if err != nil {
+		if filename == "" {
+			filename = "built in rules"
+		}
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	for _, r := range rules {
+		registerCheck(r)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+)
+
+// Just enough of the SARIF 2.1.0 object model to describe our findings
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLogT struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []sarifRunT `json:"runs"`
+}
+
+type sarifRunT struct {
+	Tool                     sarifToolT                    `json:"tool"`
+	OriginalUriBaseIds       map[string]sarifArtifactLocT  `json:"originalUriBaseIds,omitempty"`
+	VersionControlProvenance []sarifVersionControlDetailsT `json:"versionControlProvenance,omitempty"`
+	Results                  []sarifResultT                `json:"results"`
+	Properties               map[string]string             `json:"properties,omitempty"`
+}
+
+type sarifToolT struct {
+	Driver sarifDriverT `json:"driver"`
+}
+
+type sarifDriverT struct {
+	Name           string       `json:"name"`
+	InformationUri string       `json:"informationUri"`
+	Rules          []sarifRuleT `json:"rules"`
+}
+
+type sarifRuleT struct {
+	ID                   string            `json:"id"`
+	ShortDescription     sarifMessageT     `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfigT  `json:"defaultConfiguration"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+type sarifRuleConfigT struct {
+	Level string `json:"level"`
+}
+
+type sarifMessageT struct {
+	Text string `json:"text"`
+}
+
+type sarifVersionControlDetailsT struct {
+	RepositoryUri string `json:"repositoryUri"`
+}
+
+type sarifResultT struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessageT     `json:"message"`
+	Locations           []sarifLocationT  `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocationT struct {
+	PhysicalLocation sarifPhysicalLocationT `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocationT struct {
+	ArtifactLocation sarifArtifactLocT `json:"artifactLocation"`
+	Region           *sarifRegionT     `json:"region,omitempty"`
+}
+
+type sarifArtifactLocT struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegionT struct {
+	StartLine   int            `json:"startLine"`
+	StartColumn int            `json:"startColumn,omitempty"`
+	Snippet     *sarifMessageT `json:"snippet,omitempty"`
+}
+
+// sarifLevel maps our severities onto the three SARIF result levels
+func sarifLevel(sl StatusLevelT) string {
+	switch sl {
+	case Medium:
+		return "warning"
+	case High, Policy:
+		return "error"
+	}
+
+	return "note"
+}
+
+func sarifRules() ([]sarifRuleT, map[string]int) {
+	var rules []sarifRuleT
+	index := map[string]int{}
+
+	for _, c := range activeChecks {
+		index[c.ID()] = len(rules)
+		rules = append(rules, sarifRuleT{
+			ID:                   c.ID(),
+			ShortDescription:     sarifMessageT{Text: c.Description()},
+			DefaultConfiguration: sarifRuleConfigT{Level: sarifLevel(c.Severity())},
+			Properties:           map[string]string{"severity": c.Severity().String()},
+		})
+	}
+
+	return rules, index
+}
+
+func sarifResult(f Finding, ruleIndex map[string]int) sarifResultT {
+	r := sarifResultT{
+		RuleID:     f.RuleID,
+		RuleIndex:  ruleIndex[f.RuleID],
+		Level:      sarifLevel(f.Severity),
+		Message:    sarifMessageT{Text: f.Message},
+		Properties: map[string]string{"severity": f.Severity.String()},
+	}
+
+	if f.Fingerprint != "" {
+		r.PartialFingerprints = map[string]string{"owaspPolicyScanner/v1": f.Fingerprint}
+	}
+
+	// Repo wide findings, such as GitHub Pages status, have no location
+	if f.File == "" {
+		return r
+	}
+
+	loc := sarifPhysicalLocationT{
+		ArtifactLocation: sarifArtifactLocT{URI: f.File, URIBaseID: "SRCROOT"},
+	}
+
+	if f.Line > 0 {
+		loc.Region = &sarifRegionT{StartLine: f.Line, StartColumn: f.Column}
+		if f.Snippet != "" {
+			loc.Region.Snippet = &sarifMessageT{Text: f.Snippet}
+		}
+	}
+
+	r.Locations = []sarifLocationT{{PhysicalLocation: loc}}
+
+	return r
+}
+
+// writeSARIF writes one run per chapter, so that each run's locations are
+// relative to the root of that chapter's repo
+func writeSARIF(scans []*chapterScanT, filename string) error {
+	rules, ruleIndex := sarifRules()
+
+	log := sarifLogT{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRunT{},
+	}
+
+	for _, c := range scans {
+		run := sarifRunT{
+			Tool: sarifToolT{Driver: sarifDriverT{
+				Name:           "owasp-policy-scanner",
+				InformationUri: "https://github.com/vanderaj/owasp-policy-scanner",
+				Rules:          rules,
+			}},
+			VersionControlProvenance: []sarifVersionControlDetailsT{
+				{RepositoryUri: repoURL(c.name)},
+			},
+			Results:    []sarifResultT{},
+			Properties: map[string]string{"chapter": c.name, "kind": c.kind},
+		}
+
+		if root, err := filepath.Abs(c.path); err == nil {
+			u := url.URL{Scheme: "file", Path: filepath.ToSlash(root) + "/"}
+			run.OriginalUriBaseIds = map[string]sarifArtifactLocT{"SRCROOT": {URI: u.String()}}
+		}
+
+		for _, f := range c.status.Findings {
+			run.Results = append(run.Results, sarifResult(f, ruleIndex))
+		}
+
+		log.Runs = append(log.Runs, run)
+	}
+
+	file, err := json.MarshalIndent(log, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, file, 0644)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// chapterScanT is the state of a single chapter scan. A worker owns it for
+// the duration of the scan, so checks never share state with other chapters.
+type chapterScanT struct {
+	name   string
+	kind   string
+	path   string
+	status *chapterStatusT
+	out    bytes.Buffer // console output, printed once the chapter is done
+
+	// groups on other event platforms found by checkNonAutomatedPlatforms
+	eventGroups []eventGroupT
+
+	// findings seen so far by fingerprint, and those suppressed by -baseline
+	fingerprints map[string]int
+	baselined    []Finding
+
+	// files changed by fixers, see applyFixes
+	fixes    map[string]*fileFixT
+	fixOrder []string
+
+	// number of checks that failed, see reportError
+	errors int
+}
+
+func (c *chapterScanT) printStatus(sl StatusLevelT, s string) {
+	writeStatus(&c.out, sl, s)
+}
+
+// reportError prints an error that stopped part of the scan. The results are
+// incomplete, so the scanner exits with exitError.
+func (c *chapterScanT) reportError(msg string, err error) {
+	c.printStatus(Info, msg+": "+err.Error())
+	c.errors++
+}
+
+type chapterDirT struct {
+	name string
+	kind string
+	path string
+}
+
+// discoverChapters lists the repos of the given kinds under root, sorted by
+// name
+func discoverChapters(root string, kinds []repoKindT) ([]chapterDirT, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapters []chapterDirT
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		kind := kindOf(e.Name())
+		if kind == nil || !containsKind(kinds, kind.name) {
+			continue
+		}
+
+		// If we are only processing some chapters, let's only do those
+		if !selectedRepo(e.Name()) {
+			continue
+		}
+
+		path := filepath.Join(root, e.Name())
+		chapters = append(chapters, chapterDirT{name: e.Name(), kind: kind.name, path: path})
+	}
+
+	// a typo in -chapter shouldn't look like a clean scan
+	for _, name := range config.chapters {
+		found := false
+		for _, chapter := range chapters {
+			found = found || chapter.name == name
+		}
+		if !found {
+			return nil, fmt.Errorf("no repo named %s in %s", name, root)
+		}
+	}
+
+	return chapters, nil
+}
+
+// selectedRepo reports whether the repo was chosen with -chapter, which is
+// all of them if -chapter isn't given
+func selectedRepo(name string) bool {
+	return len(config.chapters) == 0 || contains(config.chapters, name)
+}
+
+// checkoutRepo is the repo checked out in dir for -path. The repo is named
+// after the origin remote if there is one, as the directory could be called
+// anything, otherwise after the directory.
+func checkoutRepo(dir string) (chapterDirT, error) {
+	name := ""
+	if remote, err := git(dir, "remote", "get-url", "origin"); err == nil {
+		remote = strings.TrimSpace(strings.Replace(remote, ":", "/", -1))
+		name = strings.TrimSuffix(path.Base(remote), ".git")
+	}
+
+	if kindOf(name) == nil {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return chapterDirT{}, err
+		}
+		name = filepath.Base(abs)
+	}
+
+	kind := kindOf(name)
+	if kind == nil {
+		return chapterDirT{}, fmt.Errorf("can't tell what kind of repo %s is, the origin remote or directory name should start with www-chapter, www-project, www-committee or www-event", dir)
+	}
+
+	return chapterDirT{name: name, kind: kind.name, path: dir}, nil
+}
+
+// reposToScan returns the repo checked out in -path, or the repos of the kinds
+// in chapters/
+func reposToScan(kinds []repoKindT) ([]chapterDirT, error) {
+	if config.path == "" {
+		return discoverChapters("chapters/", kinds)
+	}
+
+	// your own clone, which -gitpull would throw away any changes to
+	if config.gitPull {
+		return nil, fmt.Errorf("-gitpull can't be used with -path")
+	}
+	// the repo kind comes from the repo itself
+	if len(config.chapters) > 0 || config.kinds != "" {
+		return nil, fmt.Errorf("-chapter and -kinds can't be used with -path, which scans only the repo in %s", config.path)
+	}
+
+	chapter, err := checkoutRepo(config.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []chapterDirT{chapter}, nil
+}
+
+// repoRootEntry is the top directory of a repo, named after the repo so that
+// checks of the repo root still match when a -path checkout is named
+// differently
+type repoRootEntry struct {
+	fs.DirEntry
+	name string
+}
+
+func (e repoRootEntry) Name() string { return e.name }
+
+func scanChapter(chapter chapterDirT) *chapterScanT {
+	return scanChapterWith(chapter, activeChecks)
+}
+
+// scanChapterWith scans the chapter with the given checks instead of those
+// selected by -enable and -disable
+func scanChapterWith(chapter chapterDirT, checks []Check) *chapterScanT {
+	c := &chapterScanT{
+		name:   chapter.name,
+		kind:   chapter.kind,
+		path:   chapter.path,
+		status: &chapterStatusT{},
+	}
+
+	fmt.Fprintln(&c.out)
+	fmt.Fprintln(&c.out, "Scanning "+c.kind+" ", c.name)
+
+	updateGit(c)
+
+	err := filepath.WalkDir(c.path, func(s string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
+		if s == c.path {
+			d = repoRootEntry{DirEntry: d, name: c.name}
+		}
+
+		for _, check := range checks {
+			if !appliesTo(check, c.kind) || !check.Matches(s, d) {
+				continue
+			}
+
+			found := len(c.status.Findings) + len(c.baselined)
+			if err := check.Run(c, s, d); err != nil {
+				c.reportError(check.ID()+" error", err)
+				continue
+			}
+
+			// only fix what the check has just found
+			fixer, ok := check.(Fixer)
+			if !ok || !(config.fix || config.dryRun) || len(c.status.Findings)+len(c.baselined) == found {
+				continue
+			}
+			if err := fixer.Fix(c, s, d); err != nil {
+				c.reportError(check.ID()+" fix error", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		c.reportError("Scan error", err)
+	}
+
+	for _, check := range checks {
+		finisher, ok := check.(chapterFinisher)
+		if !ok || !appliesTo(check, c.kind) {
+			continue
+		}
+
+		if err := finisher.Finish(c); err != nil {
+			c.reportError(check.ID()+" error", err)
+		}
+	}
+
+	if len(c.baselined) > 0 {
+		c.printStatus(Info, fmt.Sprintf("%d findings already in the baseline", len(c.baselined)))
+	}
+
+	if err := c.applyFixes(); err != nil {
+		c.reportError("Unable to fix "+c.name, err)
+	}
+
+	return c
+}
+
+// scanChapters scans the chapters using jobs workers. Each chapter's output is
+// printed as soon as it and every chapter before it are done, so the console
+// and the returned scans are in the same order as chapters.
+func scanChapters(chapters []chapterDirT, jobs int) []*chapterScanT {
+	scans := make([]*chapterScanT, len(chapters))
+	forEachOrdered(len(chapters), jobs, func(i int) {
+		scans[i] = scanChapter(chapters[i])
+	}, func(i int) {
+		os.Stdout.Write(scans[i].out.Bytes())
+	})
+
+	return scans
+}
+
+// forEachOrdered calls work for 0..n-1 using jobs workers, and then calls
+// done for each i in order, as soon as work for i and everything before it
+// has finished.
+func forEachOrdered(n int, jobs int, work func(i int), done func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				work(i)
+				close(finished[i])
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < n; i++ {
+			queue <- i
+		}
+		close(queue)
+	}()
+
+	for i := 0; i < n; i++ {
+		<-finished[i]
+		done(i)
+	}
+	wg.Wait()
+}
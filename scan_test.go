@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReposToScanPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "www-chapter-london")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		set     func()
+		wantErr string
+	}{
+		{"the checkout", func() {}, ""},
+		{"-gitpull", func() { config.gitPull = true }, "-gitpull can't be used with -path"},
+		{"-chapter", func() { config.chapters = []string{"www-chapter-paris"} }, "-chapter and -kinds can't be used with -path"},
+		{"-kinds", func() { config.kinds = "project" }, "-chapter and -kinds can't be used with -path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfig(t)
+			config.path = dir
+			tt.set()
+
+			repos, err := reposToScan(repoKinds)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := chapterDirT{name: "www-chapter-london", kind: "chapter", path: dir}
+			if len(repos) != 1 || repos[0] != want {
+				t.Errorf("repos = %+v, want %+v", repos, want)
+			}
+		})
+	}
+}
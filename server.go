@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverT serves the results of the latest scan of each repo as JSON
+//
+//	GET  /api/repos                 repos, optionally ?kind=chapter
+//	GET  /api/repos/{name}          a repo's status and findings
+//	POST /api/repos/{name}/scan     rescan a repo in the background
+//	GET  /api/findings              findings, optionally ?severity=&rule=&repo=&kind=
+//	GET  /api/runs/last             when the last full scan ran
+type serverT struct {
+	root  string
+	kinds []repoKindT
+
+	// full scans hold scanLock, and rescans of a single repo share it, so a
+	// repo is never scanned twice at once and the newest results are kept
+	scanLock sync.RWMutex
+
+	mu       sync.Mutex
+	repos    map[string]*chapterScanT
+	scanning map[string]bool
+	lastRun  runInfoT
+}
+
+// runInfoT describes a full scan of the repos
+type runInfoT struct {
+	Started        time.Time
+	Finished       time.Time
+	Running        bool
+	Repos          int
+	Findings       int
+	PolicyFindings int
+	Error          string
+}
+
+type repoSummaryT struct {
+	Name           string
+	Kind           string
+	Leaders        int
+	Meetup         string
+	GitHub         string
+	Findings       int
+	PolicyFindings int
+}
+
+type repoDetailT struct {
+	Name   string
+	Kind   string
+	Status *chapterStatusT // nil until the repo has been scanned
+}
+
+type apiErrorT struct {
+	Error string
+}
+
+func newServer(root string, kinds []repoKindT) *serverT {
+	return &serverT{
+		root:     root,
+		kinds:    kinds,
+		repos:    map[string]*chapterScanT{},
+		scanning: map[string]bool{},
+	}
+}
+
+// runServe scans the repos every -interval and serves the results on -addr.
+// It accepts all the scan flags.
+func runServe(args []string) error {
+	addr := ":8080"
+	interval := 24 * time.Hour
+
+	registerFlags()
+	flag.StringVar(&addr, "addr", addr, "Address to serve the API on")
+	flag.DurationVar(&interval, "interval", interval, "Time between scans of all the repos")
+	flag.CommandLine.Parse(args)
+
+	kinds, err := loadScanConfig()
+	if err != nil {
+		return err
+	}
+
+	s := newServer("chapters/", kinds)
+
+	go func() {
+		for {
+			if err := s.scanAll(); err != nil {
+				log.Println("scan failed:", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	log.Println("Serving the scanner API on", addr)
+	return http.ListenAndServe(addr, s)
+}
+
+// scanAll scans every repo, replacing the results of the previous scan. If
+// the repos can't be found, the previous results are kept.
+func (s *serverT) scanAll() error {
+	s.scanLock.Lock()
+	defer s.scanLock.Unlock()
+
+	s.mu.Lock()
+	s.lastRun = runInfoT{Started: time.Now(), Running: true}
+	s.mu.Unlock()
+
+	chapters, err := discoverChapters(s.root, s.kinds)
+	discovered := err == nil
+
+	var scans []*chapterScanT
+	if discovered {
+		scans = scanChapters(chapters, config.jobs)
+	}
+
+	if err == nil && config.history != "" {
+		err = appendHistory(scans, time.Now(), config.history)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := runInfoT{Started: s.lastRun.Started, Finished: time.Now(), Repos: len(scans)}
+	if discovered {
+		s.repos = map[string]*chapterScanT{}
+	}
+	for _, c := range scans {
+		s.repos[c.name] = c
+		run.Findings += len(c.status.Findings)
+		run.PolicyFindings += policyFindings(c.status)
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	s.lastRun = run
+
+	return err
+}
+
+// scanOne rescans a single repo, returning false if it is already being
+// scanned. If a full scan is running, the rescan starts once it has finished.
+func (s *serverT) scanOne(chapter chapterDirT) bool {
+	s.mu.Lock()
+	if s.scanning[chapter.name] {
+		s.mu.Unlock()
+		return false
+	}
+	s.scanning[chapter.name] = true
+	s.mu.Unlock()
+
+	go func() {
+		s.scanLock.RLock()
+		defer s.scanLock.RUnlock()
+
+		c := scanChapter(chapter)
+		os.Stdout.Write(c.out.Bytes())
+
+		s.mu.Lock()
+		s.repos[chapter.name] = c
+		delete(s.scanning, chapter.name)
+		s.mu.Unlock()
+	}()
+
+	return true
+}
+
+func policyFindings(status *chapterStatusT) int {
+	n := 0
+	for _, f := range status.Findings {
+		if f.Severity == Policy {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *serverT) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "api/repos":
+		s.handleRepos(w, r)
+	case len(parts) == 3 && parts[0] == "api" && parts[1] == "repos":
+		s.handleRepo(w, r, parts[2])
+	case len(parts) == 4 && parts[0] == "api" && parts[1] == "repos" && parts[3] == "scan":
+		s.handleScan(w, r, parts[2])
+	case path == "api/findings":
+		s.handleFindings(w, r)
+	case path == "api/runs/last":
+		s.handleLastRun(w, r)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *serverT) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && findKind(kind) == nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unknown kind %q, expected %s", kind, kindNames()))
+		return
+	}
+
+	s.mu.Lock()
+	repos := []repoSummaryT{}
+	for _, c := range s.repos {
+		if kind != "" && c.kind != kind {
+			continue
+		}
+		repos = append(repos, repoSummaryT{
+			Name:           c.name,
+			Kind:           c.kind,
+			Leaders:        c.status.Leaders,
+			Meetup:         serviceStatus(config.meetup, c.status.Meetup),
+			GitHub:         serviceStatus(config.pages, c.status.GitHub),
+			Findings:       len(c.status.Findings),
+			PolicyFindings: policyFindings(c.status),
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	writeAPI(w, http.StatusOK, repos)
+}
+
+func (s *serverT) handleRepo(w http.ResponseWriter, r *http.Request, name string) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	s.mu.Lock()
+	c, ok := s.repos[name]
+	s.mu.Unlock()
+
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "no scan of "+name)
+		return
+	}
+
+	writeAPI(w, http.StatusOK, repoDetailT{Name: c.name, Kind: c.kind, Status: c.status})
+}
+
+func (s *serverT) handleScan(w http.ResponseWriter, r *http.Request, name string) {
+	if !allowMethod(w, r, "POST") {
+		return
+	}
+
+	// only repos that are on disk can be scanned
+	chapters, err := discoverChapters(s.root, s.kinds)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, chapter := range chapters {
+		if chapter.name != name {
+			continue
+		}
+
+		if !s.scanOne(chapter) {
+			writeAPIError(w, http.StatusConflict, name+" is already being scanned")
+			return
+		}
+		writeAPI(w, http.StatusAccepted, repoDetailT{Name: chapter.name, Kind: chapter.kind})
+		return
+	}
+
+	writeAPIError(w, http.StatusNotFound, "no repo named "+name)
+}
+
+func (s *serverT) handleFindings(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	q := r.URL.Query()
+
+	var severity *StatusLevelT
+	if q.Get("severity") != "" {
+		sl, err := parseStatusLevel(q.Get("severity"))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		severity = &sl
+	}
+
+	s.mu.Lock()
+	var names []string
+	for name := range s.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	findings := []Finding{}
+	for _, name := range names {
+		c := s.repos[name]
+		if (q.Get("repo") != "" && name != q.Get("repo")) || (q.Get("kind") != "" && c.kind != q.Get("kind")) {
+			continue
+		}
+
+		for _, f := range c.status.Findings {
+			if severity != nil && f.Severity != *severity {
+				continue
+			}
+			if q.Get("rule") != "" && f.RuleID != q.Get("rule") {
+				continue
+			}
+			findings = append(findings, f)
+		}
+	}
+	s.mu.Unlock()
+
+	writeAPI(w, http.StatusOK, findings)
+}
+
+func (s *serverT) handleLastRun(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	s.mu.Lock()
+	run := s.lastRun
+	s.mu.Unlock()
+
+	writeAPI(w, http.StatusOK, run)
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeAPIError(w, http.StatusMethodNotAllowed, r.Method+" not allowed, use "+method)
+	return false
+}
+
+func writeAPI(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeAPI(w, status, apiErrorT{Error: msg})
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServerT is a server over a directory with a chapter and a project,
+// scanned by a single check that reports the first line of each index.md
+type testServerT struct {
+	*serverT
+	url  string
+	root string
+
+	// the next run of the check waits for hold to be closed, after reading
+	// the file, see holdNext
+	holdMu sync.Mutex
+	hold   chan struct{}
+}
+
+// holdNext makes the next run of the check wait, with what it read, until
+// the returned channel is closed
+func (ts *testServerT) holdNext() chan struct{} {
+	ts.holdMu.Lock()
+	defer ts.holdMu.Unlock()
+	ts.hold = make(chan struct{})
+
+	return ts.hold
+}
+
+// waitForHold waits for the check to be held up by holdNext
+func (ts *testServerT) waitForHold(t *testing.T) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		ts.holdMu.Lock()
+		held := ts.hold == nil
+		ts.holdMu.Unlock()
+		if held {
+			return
+		}
+	}
+	t.Fatal("the check wasn't held up")
+}
+
+func newTestServer(t *testing.T) *testServerT {
+	testConfig(t)
+	savedChecks := activeChecks
+	t.Cleanup(func() { activeChecks = savedChecks })
+
+	ts := &testServerT{root: t.TempDir()}
+	config.history = filepath.Join(ts.root, "history.jsonl")
+	writeIndex(t, ts.root, "www-chapter-london", "london\n")
+	writeIndex(t, ts.root, "www-project-zap", "zap\n")
+
+	activeChecks = []Check{&checkT{
+		id:       "test-index",
+		severity: Policy,
+		match:    isFileWithSuffix("index.md"),
+		run: func(c *chapterScanT, path string, d fs.DirEntry) error {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			ts.holdMu.Lock()
+			hold := ts.hold
+			ts.hold = nil
+			ts.holdMu.Unlock()
+			if hold != nil {
+				<-hold
+			}
+
+			c.reportFinding(Finding{RuleID: "test-index", File: path, Severity: Policy, Message: strings.TrimSpace(string(data))})
+			return nil
+		},
+	}}
+
+	ts.serverT = newServer(ts.root, repoKinds)
+	server := httptest.NewServer(ts.serverT)
+	t.Cleanup(server.Close)
+	ts.url = server.URL
+
+	return ts
+}
+
+func writeIndex(t *testing.T, root string, repo string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(root, repo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, repo, "index.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// call sends a request to the API, checks the status and reads the response
+// into out, if not nil
+func (ts *testServerT) call(t *testing.T, method string, path string, wantStatus int, out interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.url+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s = %s, want %d", method, path, resp.Status, wantStatus)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// waitForScans waits for rescans of single repos to finish
+func (ts *testServerT) waitForScans(t *testing.T) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		ts.mu.Lock()
+		scanning := len(ts.scanning)
+		ts.mu.Unlock()
+		if scanning == 0 {
+			return
+		}
+	}
+	t.Fatal("rescan didn't finish")
+}
+
+func (ts *testServerT) repoFindings(t *testing.T, repo string) []string {
+	t.Helper()
+	var detail repoDetailT
+	ts.call(t, "GET", "/api/repos/"+repo, http.StatusOK, &detail)
+
+	return findingMessages(detail.Status.Findings)
+}
+
+func TestServerAPI(t *testing.T) {
+	ts := newTestServer(t)
+
+	// nothing is served until the first scan
+	var repos []repoSummaryT
+	ts.call(t, "GET", "/api/repos", http.StatusOK, &repos)
+	if len(repos) != 0 {
+		t.Errorf("repos before the first scan = %+v, want none", repos)
+	}
+	ts.call(t, "GET", "/api/repos/www-chapter-london", http.StatusNotFound, nil)
+
+	if err := ts.scanAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts.call(t, "GET", "/api/repos", http.StatusOK, &repos)
+	if len(repos) != 2 || repos[0].Name != "www-chapter-london" || repos[1].Name != "www-project-zap" {
+		t.Fatalf("repos = %+v, want london and zap", repos)
+	}
+	if repos[0].Kind != "chapter" || repos[0].Findings != 1 || repos[0].PolicyFindings != 1 {
+		t.Errorf("london = %+v, want a chapter with one policy finding", repos[0])
+	}
+
+	ts.call(t, "GET", "/api/repos?kind=project", http.StatusOK, &repos)
+	if len(repos) != 1 || repos[0].Name != "www-project-zap" {
+		t.Errorf("projects = %+v, want zap", repos)
+	}
+
+	assertStrings(t, "london findings", ts.repoFindings(t, "www-chapter-london"), []string{"london"})
+
+	var findings []Finding
+	ts.call(t, "GET", "/api/findings?severity=policy&rule=test-index", http.StatusOK, &findings)
+	assertStrings(t, "findings", findingMessages(findings), []string{"london", "zap"})
+	ts.call(t, "GET", "/api/findings?repo=www-project-zap", http.StatusOK, &findings)
+	assertStrings(t, "zap findings", findingMessages(findings), []string{"zap"})
+	ts.call(t, "GET", "/api/findings?severity=low", http.StatusOK, &findings)
+	assertStrings(t, "low findings", findingMessages(findings), nil)
+
+	var run runInfoT
+	ts.call(t, "GET", "/api/runs/last", http.StatusOK, &run)
+	if run.Running || run.Repos != 2 || run.Findings != 2 || run.PolicyFindings != 2 || run.Error != "" {
+		t.Errorf("last run = %+v, want 2 repos with 2 policy findings", run)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/api/repos?kind=bad", http.StatusBadRequest},
+		{"GET", "/api/findings?severity=bad", http.StatusBadRequest},
+		{"DELETE", "/api/repos", http.StatusMethodNotAllowed},
+		{"GET", "/api/repos/www-chapter-london/scan", http.StatusMethodNotAllowed},
+		{"POST", "/api/repos/www-chapter-nowhere/scan", http.StatusNotFound},
+		{"GET", "/api/other", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var apiErr apiErrorT
+		ts.call(t, tt.method, tt.path, tt.status, &apiErr)
+		if apiErr.Error == "" {
+			t.Errorf("%s %s has no error message", tt.method, tt.path)
+		}
+	}
+}
+
+func TestServerRescan(t *testing.T) {
+	ts := newTestServer(t)
+	if err := ts.scanAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeIndex(t, ts.root, "www-chapter-london", "london again\n")
+	release := ts.holdNext()
+
+	var detail repoDetailT
+	ts.call(t, "POST", "/api/repos/www-chapter-london/scan", http.StatusAccepted, &detail)
+	if detail.Name != "www-chapter-london" || detail.Status != nil {
+		t.Errorf("scan response = %+v, want london without a status", detail)
+	}
+	ts.call(t, "POST", "/api/repos/www-chapter-london/scan", http.StatusConflict, nil)
+
+	// the previous results are served until the rescan has finished
+	assertStrings(t, "london findings", ts.repoFindings(t, "www-chapter-london"), []string{"london"})
+
+	close(release)
+	ts.waitForScans(t)
+	assertStrings(t, "london findings", ts.repoFindings(t, "www-chapter-london"), []string{"london again"})
+}
+
+func TestServerRescanWaitsForScanAll(t *testing.T) {
+	ts := newTestServer(t)
+	if err := ts.scanAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a full scan reads london, then is held up until after the rescan starts
+	release := ts.holdNext()
+	done := make(chan error)
+	go func() { done <- ts.scanAll() }()
+	ts.waitForHold(t)
+	var run runInfoT
+	ts.call(t, "GET", "/api/runs/last", http.StatusOK, &run)
+	if !run.Running {
+		t.Errorf("last run = %+v, want it running", run)
+	}
+
+	writeIndex(t, ts.root, "www-chapter-london", "london again\n")
+	ts.call(t, "POST", "/api/repos/www-chapter-london/scan", http.StatusAccepted, nil)
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	ts.waitForScans(t)
+
+	// the rescan waited for the full scan, so the full scan's older results
+	// don't replace it
+	assertStrings(t, "london findings", ts.repoFindings(t, "www-chapter-london"), []string{"london again"})
+}
+
+func TestServerScanAllError(t *testing.T) {
+	ts := newTestServer(t)
+	if err := ts.scanAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(ts.root); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.scanAll(); err == nil {
+		t.Fatal("scanAll of a missing directory succeeded")
+	}
+
+	var run runInfoT
+	ts.call(t, "GET", "/api/runs/last", http.StatusOK, &run)
+	if run.Error == "" || run.Running {
+		t.Errorf("last run = %+v, want the error", run)
+	}
+	assertStrings(t, "london findings", ts.repoFindings(t, "www-chapter-london"), []string{"london"})
+}
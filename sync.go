@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// syncResultT is the outcome of syncing a single repo
+type syncResultT struct {
+	name string
+	out  bytes.Buffer
+	err  error
+}
+
+// runSync clones or updates every website repo of the -kinds in the org into
+// -dir. A repo that fails to sync is reported, and doesn't stop the others.
+func runSync(args []string) error {
+	dir := "chapters"
+
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	fs.StringVar(&dir, "dir", dir, "Directory to clone the repos into")
+	fs.StringVar(&config.kinds, "kinds", config.kinds, "Comma separated list of repo kinds to sync: chapter, project, committee, event (default all)")
+	fs.StringVar(&config.githubkey, "githubkey", config.githubkey, "Set a GitHub API access token")
+	fs.StringVar(&config.githubURL, "githuburl", config.githubURL, "GitHub API base URL")
+	fs.StringVar(&config.gitRemote, "gitremote", config.gitRemote, "Base URL to clone the repos from")
+	fs.IntVar(&config.jobs, "jobs", config.jobs, "Number of repos to sync in parallel")
+	fs.StringVar(&config.org, "org", config.org, "GitHub organization")
+	fs.Parse(args)
+
+	kinds, err := selectedKinds()
+	if err != nil {
+		return err
+	}
+
+	repos, err := listOrgRepos(config.org)
+	if err != nil {
+		return err
+	}
+
+	var selected []githubRepoT
+	for _, r := range repos {
+		kind := kindOf(r.Name)
+		if kind != nil && containsKind(kinds, kind.name) && !r.Archived {
+			selected = append(selected, r)
+		}
+	}
+	fmt.Printf("Found %d repos in %s\n", len(selected), config.org)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	results := make([]*syncResultT, len(selected))
+	forEachOrdered(len(selected), config.jobs, func(i int) {
+		r := selected[i]
+		results[i] = &syncResultT{name: r.Name}
+		remote := fmt.Sprintf("%s/%s/%s.git", strings.TrimSuffix(config.gitRemote, "/"), config.org, r.Name)
+		results[i].err = syncRepo(&results[i].out, filepath.Join(dir, r.Name), remote, r.Default_branch)
+	}, func(i int) {
+		os.Stdout.Write(results[i].out.Bytes())
+		if results[i].err != nil {
+			fmt.Printf("Unable to sync %s: %v\n", results[i].name, results[i].err)
+		}
+	})
+
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+
+	fmt.Printf("Synced %d repos, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d repos failed to sync", failed)
+	}
+
+	return nil
+}
+
+// syncRepo clones remote into path, or if it has already been cloned, throws
+// away any local changes and resets it to the default branch. If branch is
+// empty, the default branch is looked up from the remote.
+func syncRepo(out *bytes.Buffer, path string, remote string, branch string) error {
+	if _, err := os.Stat(filepath.Join(path, ".git")); os.IsNotExist(err) {
+		// new repo, which will be the pristine state we expect
+		fmt.Fprintln(out, "Cloning", remote)
+		args := []string{"clone", "--quiet"}
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+		_, err := git("", append(args, remote, path)...)
+		return err
+	}
+
+	fmt.Fprintln(out, "Updating", path)
+
+	if _, err := git(path, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		return err
+	}
+
+	if branch == "" {
+		var err error
+		branch, err = remoteDefaultBranch(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Switch to the default branch in case the repo has master & main
+	if _, err := git(path, "checkout", "--quiet", "--force", "-B", branch, "origin/"+branch); err != nil {
+		return err
+	}
+	_, err := git(path, "reset", "--quiet", "--hard", "origin/"+branch)
+	return err
+}
+
+// remoteDefaultBranch asks origin which branch HEAD points to, newer repos
+// use main and older ones master
+func remoteDefaultBranch(path string) (string, error) {
+	output, err := git(path, "ls-remote", "--symref", "origin", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+			return strings.TrimPrefix(fields[1], "refs/heads/"), nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to find the default branch of %s", path)
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGit runs git in dir as a test user, failing the test if it fails
+func testGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := git(dir, append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(output)
+}
+
+// newBareRepo makes remote/org/name.git with a README on branch, and
+// returns a working clone to push more commits from
+func newBareRepo(t *testing.T, remote string, org string, name string, branch string) string {
+	t.Helper()
+	bare := filepath.Join(remote, org, name+".git")
+	if err := os.MkdirAll(bare, 0755); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, bare, "init", "--quiet", "--bare")
+	testGit(t, bare, "symbolic-ref", "HEAD", "refs/heads/"+branch)
+
+	work := filepath.Join(t.TempDir(), name)
+	testGit(t, "", "clone", "--quiet", bare, work)
+	testGit(t, work, "checkout", "--quiet", "-b", branch)
+	commitFile(t, work, "README.md", "# "+name+"\n")
+	testGit(t, work, "push", "--quiet", "origin", branch)
+
+	return work
+}
+
+func commitFile(t *testing.T, work string, name string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, work, "add", name)
+	testGit(t, work, "commit", "--quiet", "-m", "Update "+name)
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+// fakeGitHubRepos is a GitHub API listing repos in the OWASP org, over two
+// pages
+func fakeGitHubRepos(t *testing.T, repos []githubRepoT) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/OWASP/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		page := repos[:len(repos)/2]
+		if r.URL.Query().Get("page") == "2" {
+			page = repos[len(repos)/2:]
+		} else {
+			w.Header().Set("Link", `<`+server.URL+`/orgs/OWASP/repos?page=2>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRunSync(t *testing.T) {
+	testConfig(t)
+	remote := t.TempDir()
+	london := newBareRepo(t, remote, "OWASP", "www-chapter-london", "main")
+	newBareRepo(t, remote, "OWASP", "www-chapter-paris", "master")
+	newBareRepo(t, remote, "OWASP", "www-project-zap", "main")
+
+	server := fakeGitHubRepos(t, []githubRepoT{
+		{Name: "www-chapter-london", Default_branch: "main"},
+		{Name: "www-chapter-old", Default_branch: "main", Archived: true},
+		{Name: "www-project-zap", Default_branch: "main"},
+		{Name: "owasp.github.io", Default_branch: "main"},
+		{Name: "www-chapter-paris", Default_branch: "master"},
+	})
+
+	dir := filepath.Join(t.TempDir(), "chapters")
+	args := []string{"-dir", dir, "-kinds", "chapter", "-githuburl", server.URL, "-gitremote", remote}
+	if err := runSync(args); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assertStrings(t, "synced", names, []string{"www-chapter-london", "www-chapter-paris"})
+
+	// a second sync throws away local changes and picks up new commits
+	clone := filepath.Join(dir, "www-chapter-london")
+	commitFile(t, london, "index.md", "new\n")
+	testGit(t, london, "push", "--quiet", "origin", "main")
+	if err := ioutil.WriteFile(filepath.Join(clone, "README.md"), []byte("local edit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testGit(t, clone, "checkout", "--quiet", "-b", "elsewhere")
+
+	if err := runSync(args); err != nil {
+		t.Fatal(err)
+	}
+	if got := testGit(t, clone, "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("branch = %q, want main", got)
+	}
+	if got := readFile(t, filepath.Join(clone, "README.md")); got != "# www-chapter-london\n" {
+		t.Errorf("README.md = %q, want the local edit thrown away", got)
+	}
+	if got := readFile(t, filepath.Join(clone, "index.md")); got != "new\n" {
+		t.Errorf("index.md = %q, want the new commit", got)
+	}
+}
+
+func TestRunSyncFailure(t *testing.T) {
+	testConfig(t)
+	remote := t.TempDir()
+	newBareRepo(t, remote, "OWASP", "www-chapter-london", "main")
+
+	server := fakeGitHubRepos(t, []githubRepoT{
+		{Name: "www-chapter-gone", Default_branch: "main"},
+		{Name: "www-chapter-london", Default_branch: "main"},
+	})
+
+	dir := t.TempDir()
+	err := runSync([]string{"-dir", dir, "-githuburl", server.URL, "-gitremote", remote})
+	if err == nil || err.Error() != "1 repos failed to sync" {
+		t.Errorf("err = %v, want 1 repos failed to sync", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "www-chapter-london", "README.md")); err != nil {
+		t.Errorf("www-chapter-london wasn't synced after www-chapter-gone failed: %v", err)
+	}
+}
+
+func TestSyncRepoDefaultBranch(t *testing.T) {
+	remote := t.TempDir()
+	work := newBareRepo(t, remote, "OWASP", "www-chapter-paris", "master")
+	bare := filepath.Join(remote, "OWASP", "www-chapter-paris.git")
+	path := filepath.Join(t.TempDir(), "www-chapter-paris")
+
+	var out bytes.Buffer
+	if err := syncRepo(&out, path, bare, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a branch, updating asks the remote for its default branch
+	commitFile(t, work, "index.md", "new\n")
+	testGit(t, work, "push", "--quiet", "origin", "master")
+	testGit(t, path, "checkout", "--quiet", "-b", "elsewhere")
+	if err := syncRepo(&out, path, bare, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := testGit(t, path, "rev-parse", "--abbrev-ref", "HEAD"); got != "master" {
+		t.Errorf("branch = %q, want master", got)
+	}
+	if got := readFile(t, filepath.Join(path, "index.md")); got != "new\n" {
+		t.Errorf("index.md = %q, want the new commit", got)
+	}
+
+	want := "Cloning " + bare + "\nUpdating " + path + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
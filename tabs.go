@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tab filename and title metadata is incorrect
+//
+// Tabs are only shown on the chapter page when they are tagged with the same
+// tag as index.md, so this runs once per chapter against all the tab files.
+func checkTabTags(c *chapterScanT, s string, d fs.DirEntry) error {
+	tabs, err := filepath.Glob(filepath.Join(s, "tab_*.md"))
+	if err != nil {
+		return err
+	}
+
+	// no tabs, nothing to be mis-tagged
+	if len(tabs) == 0 {
+		return nil
+	}
+
+	found := func(sl StatusLevelT, filename string, line int, msg string) {
+		c.reportFinding(Finding{
+			RuleID:   "tab-tags",
+			Severity: sl,
+			File:     filename,
+			Line:     line,
+			Message:  msg,
+		})
+		c.status.TabTags = true
+	}
+
+	// find the tag in index.md
+	index := filepath.Join(s, "index.md")
+	indexFm, err := parseFrontMatter(index)
+	if err != nil {
+		found(Medium, index, 1, "Unable to read tags: "+err.Error())
+		return nil
+	}
+
+	var indexTags []string
+	if indexFm != nil {
+		indexTags = indexFm.list("tags")
+	}
+
+	// if no tag, but tab_files exist, display an error and exit
+	if len(indexTags) == 0 {
+		found(Medium, index, 1, fmt.Sprintf("No tags in %s, so none of the %d tabs will be shown", index, len(tabs)))
+		return nil
+	}
+
+	// search all tab_name.md files for tags
+	orders := map[int]string{}
+	for _, tab := range tabs {
+		fm, err := parseFrontMatter(tab)
+		if err != nil {
+			found(Medium, tab, 1, "Tab is not shown: "+err.Error())
+			continue
+		}
+
+		if fm == nil {
+			found(Medium, tab, 1, "Tab is not shown, there is no front matter in "+tab)
+			continue
+		}
+
+		if title, line := fm.get("title"); title == nil || title.Value == "" {
+			found(Medium, tab, line, "Tab has no title in "+tab)
+		}
+
+		if layout, line := fm.get("layout"); layout == nil || layout.Tag != "!!null" {
+			found(Medium, tab, line, fmt.Sprintf("Tab layout is not null in %s on line %d", tab, line))
+		}
+
+		if isTab, line := fm.get("tab"); isTab == nil || isTab.Value != "true" {
+			found(Medium, tab, line, fmt.Sprintf("Tab is not marked tab: true in %s on line %d", tab, line))
+		}
+
+		order, line := fm.get("order")
+		if order == nil {
+			found(Low, tab, line, "Tab has no order in "+tab)
+		} else if n, err := strconv.Atoi(order.Value); err != nil || order.Kind != yaml.ScalarNode {
+			found(Low, tab, line, fmt.Sprintf("Tab order %q is not a number in %s on line %d", order.Value, tab, line))
+		} else if other, ok := orders[n]; ok {
+			found(Low, tab, line, fmt.Sprintf("Tab order %d in %s on line %d is also used by %s", n, tab, line, other))
+		} else {
+			orders[n] = tab
+		}
+
+		// show any tabs that aren't tagged correctly
+		tagged := false
+		for _, tag := range fm.list("tags") {
+			if contains(indexTags, tag) {
+				tagged = true
+			}
+		}
+		if !tagged {
+			_, line := fm.get("tags")
+			found(Medium, tab, line, fmt.Sprintf("Tab is not tagged %s in %s on line %d", indexTags[0], tab, line))
+		}
+	}
+
+	return nil
+}